@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func (m *TodoModel) saveArchive() {
+	saveArchiveToFile(m.archive)
+}
+
+// archiveCompleted moves the todos completed before the given time, or all
+// completed todos if it is zero, to the archive. Todos with open subtasks
+// stay until those are done. It returns the number of todos archived.
+func (m *TodoModel) archiveCompleted(before time.Time) int {
+	open := map[int]bool{}
+	for _, todo := range m.todos {
+		if !todo.Completed {
+			for steps, id := 0, todo.ParentID; id != 0 && steps < len(m.todos); steps++ {
+				open[id] = true
+				if i := m.findTodo(id); i >= 0 {
+					id = m.todos[i].ParentID
+				} else {
+					id = 0
+				}
+			}
+		}
+	}
+
+	archived := map[int]bool{}
+	for _, todo := range m.todos {
+		if todo.Completed && !open[todo.ID] && (before.IsZero() || todo.CompletedAt.Before(before)) {
+			archived[todo.ID] = true
+		}
+	}
+	if len(archived) == 0 {
+		return 0
+	}
+
+	m.rememberChange("archive " + todoCount(len(archived)))
+	var kept []TodoItem
+	for _, todo := range m.todos {
+		if archived[todo.ID] {
+			todo.Focus = false
+			m.archive = append(m.archive, todo)
+		} else {
+			kept = append(kept, todo)
+		}
+	}
+	m.todos = kept
+	m.updateList()
+	m.saveTodos()
+	m.saveArchive()
+	return len(archived)
+}
+
+// restoreArchived moves an archived todo, with any archived subtasks, back
+// to the end of the todo list as an open todo.
+func (m *TodoModel) restoreArchived(id int) {
+	restored := subtreeIDs(m.archive, id)
+	var todo TodoItem
+	for _, archived := range m.archive {
+		if archived.ID == id {
+			todo = archived
+		}
+	}
+	if todo.ID == 0 {
+		return
+	}
+
+	m.remember("restore", todo)
+	var kept []TodoItem
+	for _, archived := range m.archive {
+		if !restored[archived.ID] {
+			kept = append(kept, archived)
+			continue
+		}
+		if archived.ID == id {
+			archived.Completed = false
+			archived.CompletedAt = time.Time{}
+			if m.findTodo(archived.ParentID) < 0 {
+				archived.ParentID = 0
+			}
+		}
+		m.todos = append(m.todos, archived)
+	}
+	m.archive = kept
+	m.updateList()
+	m.saveTodos()
+	m.saveArchive()
+}
+
+// todoCount returns "1 todo" or "n todos".
+func todoCount(n int) string {
+	if n == 1 {
+		return "1 todo"
+	}
+	return fmt.Sprintf("%d todos", n)
+}
+
+// archiveDay is the todos completed on one day, most recent first.
+type archiveDay struct {
+	day   time.Time
+	todos []TodoItem
+}
+
+// archiveDays groups the archive by the day the todos were completed,
+// most recent day first. Todos without a completion time go last.
+func (m TodoModel) archiveDays() []archiveDay {
+	archive := make([]TodoItem, len(m.archive))
+	copy(archive, m.archive)
+	sort.SliceStable(archive, func(i, j int) bool {
+		return archive[i].CompletedAt.After(archive[j].CompletedAt)
+	})
+
+	var days []archiveDay
+	for _, todo := range archive {
+		day := time.Time{}
+		if !todo.CompletedAt.IsZero() {
+			day = startOfDay(todo.CompletedAt.Local())
+		}
+		if len(days) == 0 || !days[len(days)-1].day.Equal(day) {
+			days = append(days, archiveDay{day: day})
+		}
+		days[len(days)-1].todos = append(days[len(days)-1].todos, todo)
+	}
+	return days
+}
+
+// archivedInOrder returns the archived todos in the order they are shown.
+func (m TodoModel) archivedInOrder() []TodoItem {
+	var todos []TodoItem
+	for _, day := range m.archiveDays() {
+		todos = append(todos, day.todos...)
+	}
+	return todos
+}
+
+func (m TodoModel) updateArchive(msg tea.KeyMsg) (TodoModel, tea.Cmd) {
+	archived := m.archivedInOrder()
+	switch msg.String() {
+	case "up", "k":
+		if m.archiveCursor > 0 {
+			m.archiveCursor--
+		}
+	case "down", "j":
+		if m.archiveCursor < len(archived)-1 {
+			m.archiveCursor++
+		}
+	case "r":
+		if m.archiveCursor < len(archived) {
+			todo := archived[m.archiveCursor]
+			m.restoreArchived(todo.ID)
+			if m.archiveCursor >= len(m.archive) && m.archiveCursor > 0 {
+				m.archiveCursor--
+			}
+			return m, m.setStatus(fmt.Sprintf("Restored %q", todo.Text))
+		}
+	case "esc", "v":
+		m.mode = browsing
+	}
+	return m, nil
+}
+
+// archiveView shows the archive as a completion log, grouped by day.
+func (m TodoModel) archiveView(width int) string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+	dayStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("99")).Bold(true)
+	itemStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	var lines []string
+	selectedLine := 0
+	index := 0
+	for _, day := range m.archiveDays() {
+		label := "Earlier"
+		if !day.day.IsZero() {
+			label = day.day.Format("Monday, Jan 2 2006")
+		}
+		lines = append(lines, dayStyle.Render(fmt.Sprintf("%s · %d done", label, len(day.todos))))
+
+		for _, todo := range day.todos {
+			line := "  ✓ " + todo.Text
+			if !todo.CompletedAt.IsZero() {
+				line += todo.CompletedAt.Local().Format(" (15:04)")
+			}
+			if index == m.archiveCursor {
+				selectedLine = len(lines)
+				lines = append(lines, selectedStyle.Render("▶"+line[1:]))
+			} else {
+				lines = append(lines, itemStyle.Render(line))
+			}
+			index++
+		}
+	}
+	if len(lines) == 0 {
+		lines = []string{itemStyle.Render("Nothing archived yet. Press 'A' in the todo list to archive completed todos.")}
+	}
+
+	// Keep the selected todo in view
+	const height = 15
+	start := 0
+	if selectedLine >= height {
+		start = selectedLine - height + 1
+	}
+	end := start + height
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	body := lipgloss.NewStyle().Width(width).Render(strings.Join(lines[start:end], "\n"))
+	return lipgloss.JoinVertical(lipgloss.Left, titleStyle.Render("🗄️ Archive"), body)
+}
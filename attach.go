@@ -0,0 +1,82 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// When attached to a daemon the TimerModel only mirrors the daemon's state:
+// keys are forwarded as commands and the status is polled for display.
+
+type daemonStatusMsg struct {
+	status TimerStatus
+	err    error
+}
+
+type daemonPollMsg struct{}
+
+func daemonCommand(command string) tea.Cmd {
+	return func() tea.Msg {
+		status, err := sendDaemonCommand(command)
+		return daemonStatusMsg{status: status, err: err}
+	}
+}
+
+func pollDaemon() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return daemonPollMsg{}
+	})
+}
+
+func (m TimerModel) updateRemote(msg tea.Msg) (TimerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.pendingConfirm {
+			switch msg.String() {
+			case "y":
+				return m, daemonCommand("start")
+			case "n":
+				return m, daemonCommand("skip")
+			}
+		}
+		switch {
+		case key.Matches(msg, m.keys.Start):
+			return m, daemonCommand("toggle")
+		case key.Matches(msg, m.keys.Reset):
+			return m, daemonCommand("reset")
+		case key.Matches(msg, m.keys.End):
+			return m, daemonCommand("end")
+		}
+	case daemonPollMsg:
+		return m, tea.Batch(daemonCommand("status"), pollDaemon())
+	case daemonStatusMsg:
+		m.remoteErr = msg.err
+		if msg.err == nil {
+			m = m.applyStatus(msg.status)
+		}
+	}
+	return m, nil
+}
+
+// applyStatus copies a status reported by the daemon into the model so the
+// regular views can render it.
+func (m TimerModel) applyStatus(status TimerStatus) TimerModel {
+	m.sessionType = parseSessionType(status.Session)
+	m.sessionCount = status.SessionCount
+	m.isRunning = status.Running
+	m.pendingConfirm = status.Confirm
+	// Only whether the session started matters for display
+	if !status.Started {
+		m.sessionStart = time.Time{}
+	} else if m.sessionStart.IsZero() {
+		m.sessionStart = time.Now()
+	}
+	m.timer.Timeout = time.Duration(status.Remaining) * time.Second
+
+	// The daemon owns the cycle; mirror just the current segment
+	m.cycle = []segment{{m.sessionType, time.Duration(status.Duration) * time.Second}}
+	m.cycleIndex = 0
+	return m
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// suspendThreshold is the longest gap between two ticks that is still
+// treated as normal scheduling jitter. Longer gaps mean the machine was
+// suspended (or the process stopped) and the suspend policy applies.
+const suspendThreshold = 30 * time.Second
+
+type suspendPolicy int
+
+const (
+	// suspendCount lets suspended time count towards the session, keeping
+	// the countdown in line with the wall clock.
+	suspendCount suspendPolicy = iota
+	// suspendPause pauses the session at the moment the gap began.
+	suspendPause
+	// suspendAsk pauses the session and asks whether the gap should count.
+	suspendAsk
+)
+
+func (p suspendPolicy) String() string {
+	switch p {
+	case suspendPause:
+		return "pause"
+	case suspendAsk:
+		return "ask"
+	default:
+		return "count"
+	}
+}
+
+func parseSuspendPolicy(name string) (suspendPolicy, error) {
+	switch name {
+	case "count":
+		return suspendCount, nil
+	case "pause":
+		return suspendPause, nil
+	case "ask":
+		return suspendAsk, nil
+	default:
+		return suspendCount, fmt.Errorf("unknown suspend policy %q (expected count, pause or ask)", name)
+	}
+}
+
+// wallClock returns the current time without its monotonic clock reading.
+// The monotonic clock stops while the machine is suspended, so durations
+// between wall clock readings are needed to notice time spent asleep.
+func wallClock() time.Time {
+	return time.Now().Round(0)
+}
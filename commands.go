@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func printUsage() {
+	fmt.Println(`Usage: pom [command] [options]
+
+Commands:
+  (none)            Start the timer UI
+  attach            Start the UI attached to a running daemon
+  daemon            Run the timer headless with a control socket
+  status [--json]   Show the current session
+  start             Start or resume the current session
+  pause             Pause the current session
+  reset             Reset the current session to its full duration
+  end               End the current session and move to the next
+  todo add TEXT     Add a todo
+  todo list         List todos
+  todo done ID      Mark a todo as done
+  todo archive      Move completed todos to the archive
+  todo import FILE  Add the todos of a todo.txt file
+  todo export [FILE]
+                    Write the todos in todo.txt format
+  todo scan [--import]
+                    List TODO and FIXME comments that are not todos yet
+  statusline        Print the timer for status bars (tmux, polybar, waybar)
+
+Run "pom -h" or "pom <command> -h" for options.`)
+}
+
+func daemonAvailable() bool {
+	socketPath, err := getSocketPath()
+	if err != nil {
+		return false
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// applyTimerCommand runs a timer command against the daemon when one is
+// running, and against the saved state of the current directory otherwise.
+func applyTimerCommand(command string, timer TimerModel) (TimerStatus, error) {
+	if daemonAvailable() {
+		return sendDaemonCommand(command)
+	}
+
+	state, err := loadTimerState()
+	if err != nil {
+		return TimerStatus{}, err
+	}
+	var recorded tea.Cmd
+	if state != nil {
+		timer, recorded = timer.restore(*state)
+	}
+
+	timer, cmd, err := timer.control(command)
+	if err != nil {
+		return TimerStatus{}, err
+	}
+	timer.saveState()
+
+	// Run the hooks for the change and credit finished sessions before
+	// exiting
+	for _, msg := range runCmdNow(tea.Batch(recorded, cmd)) {
+		switch msg := msg.(type) {
+		case timerWarningMsg:
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", msg.err)
+		case sessionRecordedMsg:
+			todos := NewTodoModel()
+			todos.creditFocus(msg.record)
+		}
+	}
+	return timer.Status(), nil
+}
+
+// runCmdNow runs a command, including everything batched into it, and
+// returns the resulting messages. It is for use outside of a Bubble Tea
+// program, so it must not be given commands that tick.
+func runCmdNow(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, runCmdNow(c)...)
+		}
+		return msgs
+	}
+	return []tea.Msg{msg}
+}
+
+func formatClock(seconds int) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+func (s TimerStatus) String() string {
+	state := "paused"
+	if s.Running {
+		state = "running"
+	} else if !s.Started {
+		state = "waiting"
+	}
+	return fmt.Sprintf("%s %s %s (%s) | Sessions: %d", s.Emoji, s.Name, formatClock(s.Remaining), state, s.SessionCount)
+}
+
+func runTimerCommand(command string, args []string) {
+	fs := flag.NewFlagSet("pom "+command, flag.ExitOnError)
+	cfg := mustLoadConfig()
+	newTimer := addTimerFlags(fs, &cfg)
+	jsonFlag := fs.Bool("json", false, "Print the status as JSON")
+	fs.Parse(args)
+
+	status, err := applyTimerCommand(command, newTimer())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *jsonFlag {
+		data, _ := json.Marshal(status)
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Println(status)
+}
+
+func runTodoCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: pom todo add TEXT | pom todo list | pom todo done ID | pom todo archive | pom todo import FILE | pom todo export [FILE] | pom todo scan [--import]")
+		os.Exit(1)
+	}
+
+	todos := NewTodoModel()
+	switch args[0] {
+	case "add":
+		text := strings.TrimSpace(strings.Join(args[1:], " "))
+		if parseTodoInput(text).Text == "" {
+			fmt.Println("Error: todo text is empty")
+			os.Exit(1)
+		}
+		todos.addTodo(text)
+		added := todos.todos[len(todos.todos)-1]
+		fmt.Printf("Added %d: %s\n", added.ID, added.Text)
+	case "list":
+		sort, _ := parseTodoSort(mustLoadConfig().Todos.Sort)
+		for _, todo := range arrangeTodos(todos.todos, sort, true) {
+			check := " "
+			if todo.Completed {
+				check = "x"
+			}
+			indent := strings.Repeat("  ", todo.depth)
+			source := ""
+			if todo.Source.Gone {
+				source = fmt.Sprintf(" (%s, gone)", todo.Source)
+			} else if todo.Source.File != "" {
+				source = fmt.Sprintf(" (%s)", todo.Source)
+			}
+			fmt.Printf("%s[%s] %d: %s%s\n", indent, check, todo.ID, todo.inputText(), source)
+		}
+	case "done":
+		if len(args) < 2 {
+			fmt.Println("Usage: pom todo done ID")
+			os.Exit(1)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Error: invalid todo id %q\n", args[1])
+			os.Exit(1)
+		}
+		index := todos.findTodo(id)
+		if index < 0 {
+			fmt.Printf("Error: no todo with id %d\n", id)
+			os.Exit(1)
+		}
+		if !todos.todos[index].Completed {
+			todos.webhooks = NewWebhooks(mustLoadConfig().Webhooks)
+			runCmdNow(todos.toggleTodo(index))
+		}
+		fmt.Printf("Done %d: %s\n", id, todos.todos[index].Text)
+	case "archive":
+		fmt.Printf("Archived %s\n", todoCount(todos.archiveCompleted(time.Time{})))
+	case "import":
+		if len(args) < 2 {
+			fmt.Println("Usage: pom todo import FILE")
+			os.Exit(1)
+		}
+		file := os.Stdin
+		if args[1] != "-" {
+			var err error
+			if file, err = os.Open(args[1]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer file.Close()
+		}
+		imported, err := readTodoTxt(file)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Imported %s\n", todoCount(todos.importTodos(imported)))
+	case "export":
+		text := formatTodoTxt(todos.todos)
+		if len(args) < 2 || args[1] == "-" {
+			fmt.Print(text)
+			return
+		}
+		if err := os.WriteFile(args[1], []byte(text), 0644); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %s to %s\n", todoCount(len(todos.todos)), args[1])
+	case "scan":
+		importing := len(args) > 1 && (args[1] == "--import" || args[1] == "-import")
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		comments, err := scanComments(cwd)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		scan := todos.compareComments(comments)
+		for _, id := range scan.gone {
+			if i := todos.findTodo(id); i >= 0 && !todos.todos[i].Completed {
+				fmt.Printf("Gone %d: %s (%s)\n", id, todos.todos[i].Text, todos.todos[i].Source)
+			}
+		}
+		if !importing {
+			for _, comment := range scan.found {
+				fmt.Printf("New %s:%d: %s\n", comment.File, comment.Line, comment.Text)
+			}
+			if len(scan.found) > 0 {
+				fmt.Println(`Run "pom todo scan --import" to add them as todos`)
+			}
+			return
+		}
+
+		first := todos.nextID
+		todos.importComments(comments, scan.found)
+		for _, todo := range todos.todos {
+			if todo.ID >= first {
+				fmt.Printf("Added %d: %s (%s)\n", todo.ID, todo.Text, todo.Source)
+			}
+		}
+	default:
+		fmt.Printf("Unknown todo command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
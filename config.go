@@ -0,0 +1,319 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// projectConfigName is the per-project configuration file, looked up in the
+// working directory. Its settings override the user configuration.
+const projectConfigName = ".pom.toml"
+
+// Config holds the settings read from the configuration files. Command line
+// flags take precedence over it.
+type Config struct {
+	Work              configDuration     `toml:"work"`
+	ShortBreak        configDuration     `toml:"short_break"`
+	LongBreak         configDuration     `toml:"long_break"`
+	LongBreakInterval int                `toml:"long_break_interval"`
+	Cycle             string             `toml:"cycle"`
+	Profile           string             `toml:"profile"`
+	Profiles          map[string]string  `toml:"profiles"`
+	ProgressLines     int                `toml:"progress_lines"`
+	AutoStart         bool               `toml:"auto_start"`
+	Transitions       TransitionConfig   `toml:"transitions"`
+	Suspend           string             `toml:"suspend"`
+	Notifications     NotificationConfig `toml:"notifications"`
+	Hooks             HookConfig         `toml:"hooks"`
+	Webhooks          []WebhookConfig    `toml:"webhooks"`
+	Todos             TodoConfig         `toml:"todos"`
+	Colors            ColorConfig        `toml:"colors"`
+	Keys              KeyConfig          `toml:"keys"`
+}
+
+// TransitionConfig holds the start policy ("auto", "manual" or "confirm")
+// for each kind of transition. Empty policies follow auto_start.
+type TransitionConfig struct {
+	WorkToBreak string `toml:"work_to_break"`
+	BreakToWork string `toml:"break_to_work"`
+}
+
+type ColorConfig struct {
+	GradientStart string `toml:"gradient_start"`
+	GradientEnd   string `toml:"gradient_end"`
+}
+
+type KeyConfig struct {
+	Start      []string `toml:"start"`
+	Reset      []string `toml:"reset"`
+	End        []string `toml:"end"`
+	SwitchView []string `toml:"switch_view"`
+	Quit       []string `toml:"quit"`
+}
+
+// TodoConfig holds the settings of the todo list. Store is where the todos
+// are kept: "json" for pom's data directory, or "todo.txt" or "markdown"
+// for the file named by File.
+type TodoConfig struct {
+	Sort        string `toml:"sort"`
+	AutoArchive bool   `toml:"auto_archive"`
+	Store       string `toml:"store"`
+	File        string `toml:"file"`
+}
+
+// configDuration is a time.Duration written as a string such as "25m".
+type configDuration struct {
+	time.Duration
+}
+
+func (d *configDuration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Work:              configDuration{25 * time.Minute},
+		ShortBreak:        configDuration{5 * time.Minute},
+		LongBreak:         configDuration{15 * time.Minute},
+		LongBreakInterval: 4,
+		ProgressLines:     5,
+		AutoStart:         true,
+		Suspend:           "count",
+		Notifications: NotificationConfig{
+			Enabled:    true,
+			Fallback:   "bell",
+			Work:       true,
+			ShortBreak: true,
+			LongBreak:  true,
+		},
+		Hooks: HookConfig{
+			Timeout: configDuration{10 * time.Second},
+		},
+		Todos: TodoConfig{
+			Sort:  "manual",
+			Store: "json",
+		},
+		Colors: ColorConfig{
+			GradientStart: "#FF7CCB",
+			GradientEnd:   "#FDFF8C",
+		},
+		Keys: KeyConfig{
+			Start:      []string{"space"},
+			Reset:      []string{"r"},
+			End:        []string{"e"},
+			SwitchView: []string{"tab"},
+			Quit:       []string{"q", "ctrl+c"},
+		},
+	}
+}
+
+func getConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "pom"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "pom"), nil
+}
+
+// configFiles returns the configuration files in the order they are
+// applied: the user configuration first, then the project one.
+func configFiles() []string {
+	var files []string
+	if dir, err := getConfigDir(); err == nil {
+		files = append(files, filepath.Join(dir, "config.toml"))
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		files = append(files, filepath.Join(cwd, projectConfigName))
+	}
+	return files
+}
+
+// userOnlySettings are the settings a project configuration may not make.
+// It comes with the repository it is in, so it must not be able to run
+// commands or send data elsewhere.
+var userOnlySettings = []string{"hooks", "webhooks"}
+
+// checkProjectConfig reports settings of a project configuration that can
+// only be made in the user configuration. The todo file of a project must
+// also be in the project, so pom does not rewrite files elsewhere.
+func checkProjectConfig(cfg Config, md toml.MetaData) error {
+	for _, key := range userOnlySettings {
+		if md.IsDefined(key) {
+			return fmt.Errorf("%s can only be set in the user configuration, not in %s", key, projectConfigName)
+		}
+	}
+	if md.IsDefined("todos", "file") && filepath.IsAbs(cfg.Todos.File) {
+		return fmt.Errorf("todos.file must be relative to the working directory in %s", projectConfigName)
+	}
+	return nil
+}
+
+// loadConfig reads the configuration files on top of the defaults. Missing
+// files are skipped; unknown settings, invalid values and settings a
+// project configuration may not make are errors.
+func loadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	for _, filename := range configFiles() {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			continue
+		}
+
+		md, err := toml.DecodeFile(filename, &cfg)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %v", filename, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return cfg, fmt.Errorf("%s: unknown setting %q", filename, undecoded[0].String())
+		}
+		if filepath.Base(filename) == projectConfigName {
+			if err := checkProjectConfig(cfg, md); err != nil {
+				return cfg, fmt.Errorf("%s: %v", filename, err)
+			}
+		}
+		if err := cfg.validate(); err != nil {
+			return cfg, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// mustLoadConfig loads the configuration and exits with a message if it is
+// invalid.
+func mustLoadConfig() Config {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error in configuration: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func (c Config) validate() error {
+	var problems []string
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"work", c.Work.Duration},
+		{"short_break", c.ShortBreak.Duration},
+		{"long_break", c.LongBreak.Duration},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s must be greater than zero", d.name))
+		}
+	}
+
+	if c.LongBreakInterval < 1 {
+		problems = append(problems, "long_break_interval must be at least 1")
+	}
+	for name, spec := range c.Profiles {
+		if _, err := parseCycle(spec); err != nil {
+			problems = append(problems, fmt.Sprintf("profiles.%s: %v", name, err))
+		}
+	}
+	if _, err := c.resolveCycle(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.ProgressLines < 1 || c.ProgressLines > 50 {
+		problems = append(problems, "progress_lines must be between 1 and 50")
+	}
+	if _, _, err := c.startPolicies(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := parseSuspendPolicy(c.Suspend); err != nil {
+		problems = append(problems, fmt.Sprintf("suspend: %v", err))
+	}
+
+	if err := c.Notifications.validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := c.Hooks.validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := parseTodoSort(c.Todos.Sort); err != nil {
+		problems = append(problems, fmt.Sprintf("todos.sort: %v", err))
+	}
+	if _, err := newTodoStore(c.Todos); err != nil {
+		problems = append(problems, fmt.Sprintf("todos.store: %v", err))
+	}
+	for _, webhook := range c.Webhooks {
+		if err := webhook.validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	colors := []struct {
+		name  string
+		value string
+	}{
+		{"colors.gradient_start", c.Colors.GradientStart},
+		{"colors.gradient_end", c.Colors.GradientEnd},
+	}
+	for _, color := range colors {
+		if !hexColorPattern.MatchString(color.value) {
+			problems = append(problems, fmt.Sprintf("%s must be a colour like #FF7CCB, got %q", color.name, color.value))
+		}
+	}
+
+	bindings := []struct {
+		name string
+		keys []string
+	}{
+		{"start", c.Keys.Start},
+		{"reset", c.Keys.Reset},
+		{"end", c.Keys.End},
+		{"switch_view", c.Keys.SwitchView},
+		{"quit", c.Keys.Quit},
+	}
+	boundTo := map[string]string{}
+	for _, binding := range bindings {
+		if len(binding.keys) == 0 {
+			problems = append(problems, fmt.Sprintf("keys.%s needs at least one key", binding.name))
+		}
+		for _, k := range binding.keys {
+			if other, ok := boundTo[k]; ok {
+				problems = append(problems, fmt.Sprintf("key %q is bound to both keys.%s and keys.%s", k, other, binding.name))
+			}
+			boundTo[k] = binding.name
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// shortDuration formats a duration without trailing zero units, e.g. "25m"
+// instead of "25m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withConfig writes the user and project configuration files, skipping
+// empty ones, and makes pom read them.
+func withConfig(t *testing.T, user, project string) {
+	t.Helper()
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if user != "" {
+		if err := os.MkdirAll(filepath.Join(configHome, "pom"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(configHome, "pom", "config.toml"), []byte(user), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if project != "" {
+		if err := os.WriteFile(filepath.Join(dir, projectConfigName), []byte(project), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProjectConfigCannotSetHooks(t *testing.T) {
+	hooks := "[hooks]\nwork_start = [\"touch pwned\"]\n"
+
+	withConfig(t, hooks, "")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("hooks in the user configuration: %v", err)
+	}
+	if len(cfg.Hooks.WorkStart) != 1 {
+		t.Errorf("got work_start hooks %q", cfg.Hooks.WorkStart)
+	}
+
+	withConfig(t, "", "work = \"50m\"\n"+hooks)
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "hooks can only") {
+		t.Errorf("hooks in the project configuration: got error %v", err)
+	}
+}
+
+func TestProjectConfigCannotSetWebhooks(t *testing.T) {
+	webhooks := "[[webhooks]]\nurl = \"https://example.com/collect\"\n"
+
+	withConfig(t, webhooks, "")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("webhooks in the user configuration: %v", err)
+	}
+	if len(cfg.Webhooks) != 1 {
+		t.Errorf("got webhooks %+v", cfg.Webhooks)
+	}
+
+	withConfig(t, "", webhooks)
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "webhooks can only") {
+		t.Errorf("webhooks in the project configuration: got error %v", err)
+	}
+}
+
+func TestProjectTodoFileStaysInProject(t *testing.T) {
+	tests := []struct {
+		user, project string
+		ok            bool
+	}{
+		{"", "[todos]\nstore = \"todo.txt\"\nfile = \"docs/todo.txt\"\n", true},
+		{"", "[todos]\nstore = \"todo.txt\"\nfile = \"../todo.txt\"\n", false},
+		{"", "[todos]\nstore = \"todo.txt\"\nfile = \"/tmp/todo.txt\"\n", false},
+		{"[todos]\nstore = \"todo.txt\"\nfile = \"/tmp/todo.txt\"\n", "", true},
+		{"[todos]\nfile = \"/tmp/todo.txt\"\n", "[todos]\nstore = \"todo.txt\"\n", true},
+	}
+	for _, tt := range tests {
+		withConfig(t, tt.user, tt.project)
+		if _, err := loadConfig(); (err == nil) != tt.ok {
+			t.Errorf("user %q, project %q: got error %v", tt.user, tt.project, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// segment is one step of a cycle: a session type and how long it lasts.
+type segment struct {
+	kind     sessionType
+	duration time.Duration
+}
+
+// startPolicy decides what happens when a session runs out and the next
+// one is reached.
+type startPolicy int
+
+const (
+	// startAuto starts the next session right away.
+	startAuto startPolicy = iota
+	// startManual waits for the user to start the next session.
+	startManual
+	// startConfirm asks whether to start the next session or skip it.
+	startConfirm
+)
+
+func parseStartPolicy(name string) (startPolicy, error) {
+	switch name {
+	case "auto":
+		return startAuto, nil
+	case "manual":
+		return startManual, nil
+	case "confirm":
+		return startConfirm, nil
+	default:
+		return startAuto, fmt.Errorf("unknown start policy %q (expected auto, manual or confirm)", name)
+	}
+}
+
+// startPolicies returns the policies for reaching a break and for reaching
+// a work session. Transitions without their own policy follow auto_start.
+func (c Config) startPolicies() (toBreak, toWork startPolicy, err error) {
+	fallback := "manual"
+	if c.AutoStart {
+		fallback = "auto"
+	}
+
+	toBreakName, toWorkName := c.Transitions.WorkToBreak, c.Transitions.BreakToWork
+	if toBreakName == "" {
+		toBreakName = fallback
+	}
+	if toWorkName == "" {
+		toWorkName = fallback
+	}
+
+	if toBreak, err = parseStartPolicy(toBreakName); err != nil {
+		return toBreak, toWork, fmt.Errorf("transitions.work_to_break: %v", err)
+	}
+	if toWork, err = parseStartPolicy(toWorkName); err != nil {
+		return toBreak, toWork, fmt.Errorf("transitions.break_to_work: %v", err)
+	}
+	return toBreak, toWork, nil
+}
+
+// builtinProfiles are the named cycles available without configuration.
+// Profiles in the configuration file with the same name replace them.
+var builtinProfiles = map[string]string{
+	"classic":   "25w,5b,25w,5b,25w,5b,25w,15l",
+	"deep-work": "50w,10b,50w,10b,50w,30l",
+	"sprint":    "15w,3b,15w,3b,15w,3b,15w,10l",
+}
+
+// defaultCycle builds the traditional cycle of work sessions separated by
+// short breaks, with a long break after every interval-th work session.
+func defaultCycle(workDuration, shortBreakDuration, longBreakDuration time.Duration, interval int) []segment {
+	cycle := []segment{}
+	for i := 1; i <= interval; i++ {
+		cycle = append(cycle, segment{work, workDuration})
+		if i == interval {
+			cycle = append(cycle, segment{longBreak, longBreakDuration})
+		} else {
+			cycle = append(cycle, segment{shortBreak, shortBreakDuration})
+		}
+	}
+	return cycle
+}
+
+// parseCycle parses a comma separated cycle such as "50w,10b,50w,30l". Each
+// segment is a duration followed by w (work), b (short break) or l (long
+// break). Durations without a unit are minutes.
+func parseCycle(spec string) ([]segment, error) {
+	cycle := []segment{}
+	hasWork := false
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) < 2 {
+			return nil, fmt.Errorf("invalid cycle segment %q", part)
+		}
+
+		var kind sessionType
+		switch part[len(part)-1] {
+		case 'w':
+			kind = work
+			hasWork = true
+		case 'b':
+			kind = shortBreak
+		case 'l':
+			kind = longBreak
+		default:
+			return nil, fmt.Errorf("cycle segment %q must end in w, b or l", part)
+		}
+
+		value := part[:len(part)-1]
+		var duration time.Duration
+		if minutes, err := strconv.Atoi(value); err == nil {
+			duration = time.Duration(minutes) * time.Minute
+		} else if parsed, err := time.ParseDuration(value); err == nil {
+			duration = parsed
+		} else {
+			return nil, fmt.Errorf("invalid duration in cycle segment %q", part)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("cycle segment %q must be longer than zero", part)
+		}
+
+		cycle = append(cycle, segment{kind, duration})
+	}
+
+	if !hasWork {
+		return nil, fmt.Errorf("cycle %q has no work segment", spec)
+	}
+	return cycle, nil
+}
+
+// profileNames lists the built-in and configured profiles.
+func (c Config) profileNames() []string {
+	names := []string{}
+	for name := range builtinProfiles {
+		if _, ok := c.Profiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveCycle returns the cycle the timer should follow: the selected
+// profile if there is one, then an explicit cycle, and otherwise the
+// traditional cycle built from the configured durations.
+func (c Config) resolveCycle() ([]segment, error) {
+	if c.Profile != "" {
+		spec, ok := c.Profiles[c.Profile]
+		if !ok {
+			spec, ok = builtinProfiles[c.Profile]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", c.Profile, strings.Join(c.profileNames(), ", "))
+		}
+		return parseCycle(spec)
+	}
+
+	if c.Cycle != "" {
+		return parseCycle(c.Cycle)
+	}
+
+	return defaultCycle(c.Work.Duration, c.ShortBreak.Duration, c.LongBreak.Duration, c.LongBreakInterval), nil
+}
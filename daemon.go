@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TimerStatus is the state of a timer as reported over the control socket.
+type TimerStatus struct {
+	Session      string `json:"session"`
+	Name         string `json:"name"`
+	Emoji        string `json:"emoji"`
+	Remaining    int    `json:"remaining_seconds"`
+	Duration     int    `json:"duration_seconds"`
+	Running      bool   `json:"running"`
+	Started      bool   `json:"started"`
+	SessionCount int    `json:"session_count"`
+	// Confirm is set while the session waits to be started or skipped
+	Confirm bool `json:"confirm,omitempty"`
+}
+
+func (m TimerModel) Status() TimerStatus {
+	remaining := m.timer.Timeout
+	if m.isRunning {
+		remaining = m.deadline.Sub(wallClock())
+	}
+
+	return TimerStatus{
+		Session:      m.sessionType.String(),
+		Name:         m.getSessionName(),
+		Emoji:        m.getSessionEmoji(),
+		Remaining:    int(remaining.Round(time.Second).Seconds()),
+		Duration:     int(m.getCurrentSessionDuration().Seconds()),
+		Running:      m.IsRunning(),
+		Started:      !m.sessionStart.IsZero(),
+		SessionCount: m.sessionCount,
+		Confirm:      m.pendingConfirm,
+	}
+}
+
+// control applies a command received over the control socket.
+func (m TimerModel) control(command string) (TimerModel, tea.Cmd, error) {
+	var cmd tea.Cmd
+	switch command {
+	case "start":
+		m, cmd = m.start()
+	case "pause":
+		m, cmd = m.pause()
+	case "toggle":
+		if m.isRunning {
+			m, cmd = m.pause()
+		} else {
+			m, cmd = m.start()
+		}
+	case "reset":
+		m, cmd = m.reset()
+	case "end":
+		m, cmd = m.end()
+	case "skip":
+		if !m.pendingConfirm {
+			return m, nil, fmt.Errorf("no session is waiting to be confirmed")
+		}
+		m, cmd = m.skip()
+	case "status":
+	default:
+		return m, nil, fmt.Errorf("unknown command %q", command)
+	}
+	return m, cmd, nil
+}
+
+func getSocketPath() (string, error) {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dataDir, "daemon.sock"), nil
+}
+
+type daemonRequest struct {
+	command string
+	reply   chan daemonResponse
+}
+
+type daemonResponse struct {
+	OK     bool         `json:"ok"`
+	Error  string       `json:"error,omitempty"`
+	Status *TimerStatus `json:"status,omitempty"`
+}
+
+// daemonModel runs the timer state machine without a user interface. All
+// changes arrive as daemonRequests sent by the socket server.
+type daemonModel struct {
+	timer    TimerModel
+	startCmd tea.Cmd
+	outbox   outboxRetrier
+}
+
+func (m daemonModel) Init() tea.Cmd {
+	return tea.Batch(m.startCmd, flushOutboxCmd())
+}
+
+func (m daemonModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if req, ok := msg.(daemonRequest); ok {
+		var cmd tea.Cmd
+		var err error
+		m.timer, cmd, err = m.timer.control(req.command)
+
+		status := m.timer.Status()
+		response := daemonResponse{OK: err == nil, Status: &status}
+		if err != nil {
+			response.Error = err.Error()
+		}
+		req.reply <- response
+		return m, cmd
+	}
+
+	switch msg := msg.(type) {
+	case outboxFlushMsg, outboxFlushedMsg:
+		cmd := m.outbox.Update(msg)
+		return m, cmd
+	case sessionRecordedMsg:
+		todos := NewTodoModel()
+		todos.creditFocus(msg.record)
+		return m, nil
+	case terminalNotificationMsg:
+		// Without a renderer nothing else writes to the terminal
+		fmt.Print(msg.seq)
+		return m, nil
+	}
+
+	if warning, ok := msg.(timerWarningMsg); ok {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", warning.err)
+	}
+
+	var cmd tea.Cmd
+	m.timer, cmd = m.timer.Update(msg)
+	return m, cmd
+}
+
+func (m daemonModel) View() string {
+	return ""
+}
+
+// runDaemon runs the timer headless and serves the control socket until
+// the process is interrupted.
+func runDaemon(timer TimerModel) error {
+	socketPath, err := getSocketPath()
+	if err != nil {
+		return err
+	}
+
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", socketPath)
+	}
+	// Remove a socket left behind by a daemon that did not shut down cleanly
+	os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	// Nobody is around to answer a question after a suspend
+	if timer.suspendPolicy == suspendAsk {
+		timer.suspendPolicy = suspendPause
+	}
+
+	var startCmd tea.Cmd
+	if state, err := loadTimerState(); err == nil && state != nil && state.worthResuming() {
+		timer, startCmd = timer.restore(*state)
+		if timer.isRunning {
+			startCmd = tea.Batch(startCmd, timer.startCountdown())
+		}
+		timer.saveState()
+	}
+
+	p := tea.NewProgram(daemonModel{timer: timer, startCmd: startCmd}, tea.WithInput(nil), tea.WithoutRenderer())
+	go serveDaemon(listener, p)
+
+	_, err = p.Run()
+	return err
+}
+
+func serveDaemon(listener net.Listener, p *tea.Program) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go handleDaemonConn(conn, p)
+	}
+}
+
+// handleDaemonConn reads one command per line and answers each with a
+// single line of JSON.
+func handleDaemonConn(conn net.Conn, p *tea.Program) {
+	defer conn.Close()
+
+	encoder := json.NewEncoder(conn)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+		if command == "" {
+			continue
+		}
+
+		reply := make(chan daemonResponse, 1)
+		p.Send(daemonRequest{command: command, reply: reply})
+
+		var response daemonResponse
+		select {
+		case response = <-reply:
+		case <-time.After(5 * time.Second):
+			response = daemonResponse{Error: "daemon did not respond"}
+		}
+
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+	}
+}
+
+// sendDaemonCommand sends a single command to a running daemon and returns
+// the timer status it reports.
+func sendDaemonCommand(command string) (TimerStatus, error) {
+	socketPath, err := getSocketPath()
+	if err != nil {
+		return TimerStatus{}, err
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return TimerStatus{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return TimerStatus{}, err
+	}
+
+	var response daemonResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return TimerStatus{}, err
+	}
+	if !response.OK {
+		return TimerStatus{}, fmt.Errorf("%s", response.Error)
+	}
+
+	return *response.Status, nil
+}
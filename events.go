@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// timerEvent describes a transition of the timer. Events are named after
+// the kind of session and what happened to it, e.g. "work_start" or
+// "break_end".
+type timerEvent struct {
+	Event        string    `json:"event"`
+	Session      string    `json:"session"`
+	Planned      int       `json:"planned_seconds"`
+	Remaining    int       `json:"remaining_seconds"`
+	Elapsed      int       `json:"elapsed_seconds"`
+	Completed    bool      `json:"completed"`
+	SessionCount int       `json:"session_count"`
+	Dir          string    `json:"dir"`
+	Time         time.Time `json:"time"`
+}
+
+// event describes the current session for the given action: start, pause,
+// resume, end, or skip for a session skipped before it started.
+func (m TimerModel) event(action string) timerEvent {
+	kind := "break"
+	if m.sessionType == work {
+		kind = "work"
+	}
+
+	dir, _ := os.Getwd()
+	return timerEvent{
+		Event:        kind + "_" + action,
+		Session:      m.sessionType.String(),
+		Planned:      int(m.getCurrentSessionDuration().Seconds()),
+		Remaining:    int(m.timer.Timeout.Round(time.Second).Seconds()),
+		Elapsed:      int(m.elapsed.Round(time.Second).Seconds()),
+		SessionCount: m.sessionCount,
+		Dir:          dir,
+		Time:         time.Now(),
+	}
+}
+
+// emit returns a command delivering the events, in order, to the
+// configured hooks, and queues them for the webhooks.
+func (m TimerModel) emit(events ...timerEvent) tea.Cmd {
+	if m.readOnly || len(events) == 0 {
+		return nil
+	}
+	return tea.Batch(m.hooks.run(events), m.webhooks.send(sessionEvents(events)...))
+}
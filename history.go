@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SessionRecord describes a single work or break session that was either
+// completed (the timer ran out) or ended early with the end key.
+type SessionRecord struct {
+	Type           string    `json:"type"`
+	PlannedSeconds int       `json:"planned_seconds"`
+	ElapsedSeconds int       `json:"elapsed_seconds"`
+	Pauses         int       `json:"pauses"`
+	StartedAt      time.Time `json:"started_at"`
+	EndedAt        time.Time `json:"ended_at"`
+	Dir            string    `json:"dir"`
+	Completed      bool      `json:"completed"`
+}
+
+// sessionRecordedMsg reports a session that was written to the history, so
+// that whoever owns the todo list can credit it to the focus todo.
+type sessionRecordedMsg struct {
+	record SessionRecord
+}
+
+func getHistoryFilename() (string, error) {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dataDir, "history.jsonl"), nil
+}
+
+// appendSessionRecord appends one record to the history log. The log is
+// stored as JSON lines so that writes never have to rewrite earlier entries.
+func appendSessionRecord(record SessionRecord) error {
+	filename, err := getHistoryFilename()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func loadSessionHistory() ([]SessionRecord, error) {
+	filename, err := getHistoryFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []SessionRecord{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []SessionRecord{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record SessionRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// Skip lines that were cut short by a crash mid-write
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// HookConfig lists shell commands to run for each timer event. Commands run
+// with sh -c; the event is passed in POM_* environment variables and as
+// JSON on stdin.
+type HookConfig struct {
+	Timeout     configDuration `toml:"timeout"`
+	WorkStart   []string       `toml:"work_start"`
+	WorkPause   []string       `toml:"work_pause"`
+	WorkResume  []string       `toml:"work_resume"`
+	WorkEnd     []string       `toml:"work_end"`
+	BreakStart  []string       `toml:"break_start"`
+	BreakPause  []string       `toml:"break_pause"`
+	BreakResume []string       `toml:"break_resume"`
+	BreakEnd    []string       `toml:"break_end"`
+}
+
+func (c HookConfig) validate() error {
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("hooks.timeout must be greater than zero")
+	}
+	return nil
+}
+
+type Hooks struct {
+	commands map[string][]string
+	timeout  time.Duration
+	queue    *hookQueue
+}
+
+// hookQueue runs hooks in the order their events happened. Bubble Tea runs
+// commands concurrently, so without it a quick pause and resume could run
+// the resume hooks before the pause hooks are done.
+type hookQueue struct {
+	mu      sync.Mutex
+	turn    *sync.Cond
+	next    int
+	serving int
+}
+
+func newHookQueue() *hookQueue {
+	q := &hookQueue{}
+	q.turn = sync.NewCond(&q.mu)
+	return q
+}
+
+// ticket takes the next place in the queue. It must be called when the
+// events happen, not from the command running the hooks.
+func (q *hookQueue) ticket() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ticket := q.next
+	q.next++
+	return ticket
+}
+
+// wait blocks until it is the ticket's turn.
+func (q *hookQueue) wait(ticket int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.serving != ticket {
+		q.turn.Wait()
+	}
+}
+
+// done passes the turn on to the next ticket.
+func (q *hookQueue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.serving++
+	q.turn.Broadcast()
+}
+
+func NewHooks(cfg HookConfig) Hooks {
+	return Hooks{
+		commands: map[string][]string{
+			"work_start":   cfg.WorkStart,
+			"work_pause":   cfg.WorkPause,
+			"work_resume":  cfg.WorkResume,
+			"work_end":     cfg.WorkEnd,
+			"break_start":  cfg.BreakStart,
+			"break_pause":  cfg.BreakPause,
+			"break_resume": cfg.BreakResume,
+			"break_end":    cfg.BreakEnd,
+		},
+		timeout: cfg.Timeout.Duration,
+		queue:   newHookQueue(),
+	}
+}
+
+// run returns a command running the hooks for the events one after another,
+// after the hooks of earlier events, or nil if none of them has hooks.
+func (h Hooks) run(events []timerEvent) tea.Cmd {
+	hasHooks := false
+	for _, ev := range events {
+		if len(h.commands[ev.Event]) > 0 {
+			hasHooks = true
+		}
+	}
+	if !hasHooks {
+		return nil
+	}
+
+	ticket := h.queue.ticket()
+	return func() tea.Msg {
+		h.queue.wait(ticket)
+		defer h.queue.done()
+
+		var failures []string
+		for _, ev := range events {
+			for _, command := range h.commands[ev.Event] {
+				if err := runHook(command, ev, h.timeout); err != nil {
+					failures = append(failures, err.Error())
+				}
+			}
+		}
+		if len(failures) > 0 {
+			return timerWarningMsg{fmt.Errorf("%s", strings.Join(failures, "; "))}
+		}
+		return nil
+	}
+}
+
+func (ev timerEvent) environ() []string {
+	return []string{
+		"POM_EVENT=" + ev.Event,
+		"POM_SESSION=" + ev.Session,
+		"POM_PLANNED_SECONDS=" + strconv.Itoa(ev.Planned),
+		"POM_REMAINING_SECONDS=" + strconv.Itoa(ev.Remaining),
+		"POM_ELAPSED_SECONDS=" + strconv.Itoa(ev.Elapsed),
+		"POM_COMPLETED=" + strconv.FormatBool(ev.Completed),
+		"POM_SESSION_COUNT=" + strconv.Itoa(ev.SessionCount),
+		"POM_DIR=" + ev.Dir,
+	}
+}
+
+func runHook(command string, ev timerEvent, timeout time.Duration) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), ev.environ()...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// Don't wait forever for background processes holding stderr open
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s hook %q timed out after %s", ev.Event, command, timeout)
+	}
+	if err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return fmt.Errorf("%s hook %q failed: %v: %s", ev.Event, command, err, detail)
+		}
+		return fmt.Errorf("%s hook %q failed: %v", ev.Event, command, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHooksRunInEventOrder(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	hooks := NewHooks(HookConfig{
+		Timeout:    configDuration{10 * time.Second},
+		WorkPause:  []string{"sleep 0.2; echo pause >> " + log},
+		WorkResume: []string{"echo resume >> " + log},
+	})
+
+	pause := hooks.run([]timerEvent{{Event: "work_pause"}})
+	resume := hooks.run([]timerEvent{{Event: "work_resume"}})
+
+	// Bubble Tea may start the commands in any order
+	var wg sync.WaitGroup
+	for _, cmd := range []func() interface{}{
+		func() interface{} { return resume() },
+		func() interface{} { return pause() },
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if msg := cmd(); msg != nil {
+				t.Errorf("hook failed: %v", msg)
+			}
+		}()
+		time.Sleep(50 * time.Millisecond)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "pause\nresume\n" {
+		t.Errorf("hooks ran as %q, want pause before resume", got)
+	}
+}
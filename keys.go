@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/key"
 )
 
@@ -10,18 +12,34 @@ type KeyMap struct {
 }
 
 func DefaultKeyMap() KeyMap {
+	return KeyMapFromConfig(DefaultConfig().Keys)
+}
+
+func KeyMapFromConfig(cfg KeyConfig) KeyMap {
 	return KeyMap{
-		Quit: key.NewBinding(
-			key.WithKeys("q", "ctrl+c"),
-			key.WithHelp("q", "quit"),
-		),
-		SwitchView: key.NewBinding(
-			key.WithKeys("tab"),
-			key.WithHelp("tab", "switch view"),
-		),
+		Quit:       newConfiguredBinding(cfg.Quit, "quit"),
+		SwitchView: newConfiguredBinding(cfg.SwitchView, "switch view"),
 	}
 }
 
+// newConfiguredBinding builds a binding from key names as written in the
+// configuration, where "space" stands for the space bar.
+func newConfiguredBinding(keys []string, help string) key.Binding {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		if k == "space" {
+			names[i] = " "
+		} else {
+			names[i] = k
+		}
+	}
+
+	return key.NewBinding(
+		key.WithKeys(names...),
+		key.WithHelp(keys[0], help),
+	)
+}
+
 func (k KeyMap) ShortHelp() string {
-	return "tab: switch view • q: quit"
+	return fmt.Sprintf("%s: switch view • %s: quit", k.SwitchView.Help().Key, k.Quit.Help().Key)
 }
@@ -4,8 +4,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -15,28 +17,48 @@ type viewState int
 const (
 	timerView viewState = iota
 	todoView
+	statsView
+	numViews
 )
 
 type model struct {
 	timer  TimerModel
 	todo   TodoModel
+	stats  StatsModel
 	view   viewState
 	keys   KeyMap
+	outbox outboxRetrier
 	width  int
 	height int
+
+	// notification is a terminal notification sequence to write with the
+	// next frame
+	notification   string
+	notificationID int
 }
 
-func initialModel(sessionDuration, shortBreakDuration, longBreakDuration time.Duration, lines int) model {
+func initialModel(timer TimerModel, cfg Config) model {
+	todo := NewTodoModel()
+	todo.webhooks = NewWebhooks(cfg.Webhooks)
+	todo.sort, _ = parseTodoSort(cfg.Todos.Sort)
+	todo.updateList()
+	if cfg.Todos.AutoArchive {
+		todo.autoArchive = true
+		todo.archiveCompleted(startOfDay(time.Now()))
+	}
+
 	return model{
-		timer: NewTimerModelWithOptions(sessionDuration, shortBreakDuration, longBreakDuration, lines),
-		todo:  NewTodoModel(),
+		timer: timer,
+		todo:  todo,
+		stats: NewStatsModel(),
 		view:  timerView,
-		keys:  DefaultKeyMap(),
+		keys:  KeyMapFromConfig(cfg.Keys),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(m.timer.Init(), m.todo.Init())
+	// Deliver webhook events left over from earlier runs
+	return tea.Batch(m.timer.Init(), m.todo.Init(), flushOutboxCmd())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -46,18 +68,51 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case outboxFlushMsg, outboxFlushedMsg:
+		cmd := m.outbox.Update(msg)
+		return m, cmd
+
+	case sessionRecordedMsg:
+		m.todo.creditFocus(msg.record)
+		return m, nil
+
+	case terminalNotificationMsg:
+		m.notification = msg.seq
+		m.notificationID++
+		return m, clearTerminalNotification(m.notificationID)
+
+	case terminalNotificationClearMsg:
+		if msg.id == m.notificationID {
+			m.notification = ""
+		}
+		return m, nil
+
+	case todoStatusClearMsg, todoDayMsg, todoStoreCheckMsg, commentsScannedMsg:
+		// The todo list needs these even when it is not shown
+		var cmd tea.Cmd
+		m.todo, cmd = m.todo.Update(msg)
+		return m, cmd
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		typing := m.view == todoView && m.todo.capturingInput()
+		switch {
+		case typing:
+		case key.Matches(msg, m.keys.Quit):
 			return m, tea.Quit
-		case "tab":
-			if m.view == timerView {
-				m.view = todoView
-			} else {
-				m.view = timerView
+		case key.Matches(msg, m.keys.SwitchView):
+			m.view = (m.view + 1) % numViews
+			if m.view == statsView {
+				m.stats.Refresh()
 			}
 			return m, nil
 		}
+
+		// Keys in the todo view are meant for the todo list only
+		if m.view == todoView {
+			var cmd tea.Cmd
+			m.todo, cmd = m.todo.Update(msg)
+			return m, cmd
+		}
 	}
 
 	var cmd tea.Cmd
@@ -66,7 +121,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Always update timer to handle tick messages
 	m.timer, timerCmd = m.timer.Update(msg)
 
-	if m.view == timerView {
+	if m.view == timerView || m.view == statsView {
 		cmd = timerCmd
 	} else {
 		m.todo, cmd = m.todo.Update(msg)
@@ -88,10 +143,13 @@ func (m model) View() string {
 		Width(width)
 
 	var content string
-	if m.view == timerView {
-		content = m.timer.ViewWithTodos(m.todo.todos)
-	} else {
+	switch m.view {
+	case timerView:
+		content = m.timer.ViewWithTodos(m.todo.visibleTodos())
+	case todoView:
 		content = m.todo.View()
+	case statsView:
+		content = m.stats.View()
 	}
 
 	help := helpStyle.Render(m.keys.ShortHelp())
@@ -110,40 +168,132 @@ func (m model) View() string {
 		Height(m.height).
 		Align(lipgloss.Center, lipgloss.Center)
 
-	return containerStyle.Render(mainContent)
+	// The sequence takes no room on screen; the last line is padding that
+	// does not change, so it is written once
+	return containerStyle.Render(mainContent) + m.notification
 }
 
-func main() {
-	sessionFlag := flag.String("s", "25m", "Session duration (e.g., 10m, 1h30m)")
-	shortBreakFlag := flag.String("sb", "5m", "Short break duration (e.g., 5m, 10m)")
-	longBreakFlag := flag.String("lb", "15m", "Long break duration (e.g., 15m, 30m)")
-	linesFlag := flag.Int("l", 5, "Number of progress bar lines")
-	flag.Parse()
-	
-	sessionDuration, err := time.ParseDuration(*sessionFlag)
+// parseTimerFlags parses the timer options shared by the UI and the daemon
+// on top of cfg and returns a timer configured with them.
+func parseTimerFlags(name string, args []string, cfg *Config) TimerModel {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	newTimer := addTimerFlags(fs, cfg)
+	fs.Parse(args)
+	return newTimer()
+}
+
+// addTimerFlags defines the timer options on fs, defaulting to the values
+// in cfg. The returned function applies the flags to cfg and builds the
+// timer once fs has been parsed.
+func addTimerFlags(fs *flag.FlagSet, cfg *Config) func() TimerModel {
+	sessionFlag := fs.String("s", shortDuration(cfg.Work.Duration), "Session duration (e.g., 10m, 1h30m)")
+	shortBreakFlag := fs.String("sb", shortDuration(cfg.ShortBreak.Duration), "Short break duration (e.g., 5m, 10m)")
+	longBreakFlag := fs.String("lb", shortDuration(cfg.LongBreak.Duration), "Long break duration (e.g., 15m, 30m)")
+	intervalFlag := fs.Int("i", cfg.LongBreakInterval, "Work sessions before a long break")
+	cycleFlag := fs.String("c", cfg.Cycle, "Custom cycle (e.g., 50w,10b,50w,10b,50w,30l)")
+	profileFlag := fs.String("p", cfg.Profile, "Named cycle profile (e.g., deep-work)")
+	linesFlag := fs.Int("l", cfg.ProgressLines, "Number of progress bar lines")
+	suspendFlag := fs.String("suspend", cfg.Suspend, "What to do after a suspend: count, pause or ask")
+
+	return func() TimerModel {
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+		// A cycle chosen on the command line replaces one from the
+		// configuration, and so do durations given without a cycle.
+		if set["c"] && !set["p"] {
+			*profileFlag = ""
+		} else if !set["c"] && !set["p"] && (set["s"] || set["sb"] || set["lb"] || set["i"]) {
+			*cycleFlag = ""
+			*profileFlag = ""
+		}
+
+		cfg.LongBreakInterval = *intervalFlag
+		cfg.Cycle = *cycleFlag
+		cfg.Profile = *profileFlag
+		applyTimerFlags(cfg, *sessionFlag, *shortBreakFlag, *longBreakFlag, *linesFlag, *suspendFlag)
+		return NewTimerModelWithConfig(*cfg)
+	}
+}
+
+func applyTimerFlags(cfg *Config, sessionFlag, shortBreakFlag, longBreakFlag string, lines int, suspendFlag string) {
+	sessionDuration, err := time.ParseDuration(sessionFlag)
 	if err != nil {
 		fmt.Printf("Error parsing session duration: %v\n", err)
 		fmt.Println("Examples: 10m, 25m, 1h, 1h30m")
 		os.Exit(1)
 	}
 	
-	shortBreakDuration, err := time.ParseDuration(*shortBreakFlag)
+	shortBreakDuration, err := time.ParseDuration(shortBreakFlag)
 	if err != nil {
 		fmt.Printf("Error parsing short break duration: %v\n", err)
 		fmt.Println("Examples: 5m, 10m, 15m")
 		os.Exit(1)
 	}
 	
-	longBreakDuration, err := time.ParseDuration(*longBreakFlag)
+	longBreakDuration, err := time.ParseDuration(longBreakFlag)
 	if err != nil {
 		fmt.Printf("Error parsing long break duration: %v\n", err)
 		fmt.Println("Examples: 15m, 30m, 45m")
 		os.Exit(1)
 	}
 	
-	p := tea.NewProgram(initialModel(sessionDuration, shortBreakDuration, longBreakDuration, *linesFlag), tea.WithAltScreen())
+	cfg.Work.Duration = sessionDuration
+	cfg.ShortBreak.Duration = shortBreakDuration
+	cfg.LongBreak.Duration = longBreakDuration
+	cfg.ProgressLines = lines
+	cfg.Suspend = suspendFlag
+	
+	if err := cfg.validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runUI(timer TimerModel, cfg Config) {
+	p := tea.NewProgram(initialModel(timer, cfg), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v", err)
 		os.Exit(1)
 	}
 }
+
+func main() {
+	args := os.Args[1:]
+	command := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "":
+		cfg := mustLoadConfig()
+		timer := parseTimerFlags("pom", args, &cfg)
+		timer.offerResume()
+		runUI(timer, cfg)
+	case "attach":
+		cfg := mustLoadConfig()
+		timer := parseTimerFlags("pom attach", args, &cfg)
+		timer.remote = true
+		runUI(timer, cfg)
+	case "daemon":
+		cfg := mustLoadConfig()
+		timer := parseTimerFlags("pom daemon", args, &cfg)
+		if err := runDaemon(timer); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "status", "start", "pause", "reset", "end":
+		runTimerCommand(command, args)
+	case "todo":
+		runTodoCommand(args)
+	case "statusline":
+		runStatusLineCommand(args)
+	case "help":
+		printUsage()
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+}
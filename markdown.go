@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Todos are kept in a Markdown file as checklist items, nested for
+// subtasks:
+//
+//	## Release
+//
+//	- [ ] Write release notes ~2 due:2025-03-14 <!-- pom id:4 created:2025-03-10 -->
+//	  - [x] Collect changes <!-- pom id:5 done:2025-03-12 -->
+//
+// The item text is what would be typed into pom, so it can be edited by
+// hand. What pom keeps track of by itself goes in a comment at the end,
+// which Markdown renderers hide. Everything but the checklist items is
+// left as it is.
+
+// markdownTodoStore keeps the todos in a Markdown file, such as a TODO.md
+// in the repository.
+type markdownTodoStore struct {
+	filename string
+}
+
+var (
+	markdownItemPattern    = regexp.MustCompile(`^([ \t]*)([-*+]) \[([ xX])\] (.*)$`)
+	markdownCommentPattern = regexp.MustCompile(`\s*<!--\s*pom\b(.*?)-->\s*$`)
+)
+
+// markdownItem is a checklist item of a Markdown file.
+type markdownItem struct {
+	todo   TodoItem
+	line   int
+	indent int
+	marker string
+	// block numbers the runs of consecutive checklist lines
+	block int
+}
+
+// parseMarkdownTodos finds the checklist items in the lines of a Markdown
+// file. Items without an ID get temporary negative ones so that their
+// subtasks can refer to them. Items in code blocks are not todos.
+func parseMarkdownTodos(lines []string, now time.Time) []markdownItem {
+	var items []markdownItem
+	// parents are the items enclosing the current line, innermost last
+	var parents []markdownItem
+	block, fenced, newID := -1, false, -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+
+		match := markdownItemPattern.FindStringSubmatch(line)
+		if fenced || match == nil || strings.TrimSpace(match[4]) == "" {
+			parents = nil
+			continue
+		}
+		if len(items) == 0 || items[len(items)-1].line != i-1 {
+			block++
+		}
+
+		item := markdownItem{
+			todo:   parseMarkdownItem(match[3], match[4], now),
+			line:   i,
+			indent: len(strings.ReplaceAll(match[1], "\t", "    ")),
+			marker: match[2],
+			block:  block,
+		}
+		if item.todo.ID == 0 {
+			item.todo.ID = newID
+			newID--
+		}
+
+		for len(parents) > 0 && parents[len(parents)-1].indent >= item.indent {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) > 0 {
+			item.todo.ParentID = parents[len(parents)-1].todo.ID
+		}
+		parents = append(parents, item)
+		items = append(items, item)
+	}
+	return items
+}
+
+// parseMarkdownItem parses the check box and text of a checklist item.
+func parseMarkdownItem(check, text string, now time.Time) TodoItem {
+	var todo TodoItem
+	todo.Completed = check != " "
+
+	if comment := markdownCommentPattern.FindStringSubmatchIndex(text); comment != nil {
+		for _, word := range strings.Fields(text[comment[2]:comment[3]]) {
+			todo.setMarkdownAttribute(word, now)
+		}
+		text = text[:comment[0]]
+	}
+
+	parseTodoInputAt(text, now).apply(&todo)
+	return todo
+}
+
+// setMarkdownAttribute sets an attribute from the comment of a checklist
+// item. Besides the todo.txt attributes it has the creation and completion
+// dates.
+func (t *TodoItem) setMarkdownAttribute(word string, now time.Time) {
+	key, value, _ := strings.Cut(word, ":")
+	switch key {
+	case "created", "done":
+		date, err := time.ParseInLocation(dueLayout, value, time.Local)
+		if err != nil {
+			return
+		}
+		if key == "created" {
+			t.CreatedAt = date
+		} else {
+			t.CompletedAt = date
+		}
+	default:
+		t.setTodoTxtAttribute(word, now)
+	}
+}
+
+// markdownItemText returns the todo as a checklist item without indent.
+func (t TodoItem) markdownItemText(marker string) string {
+	check := " "
+	if t.Completed {
+		check = "x"
+	}
+
+	attributes := []string{"pom"}
+	if t.ID > 0 {
+		attributes = append(attributes, fmt.Sprintf("id:%d", t.ID))
+	}
+	if !t.CreatedAt.IsZero() {
+		attributes = append(attributes, "created:"+t.CreatedAt.Format(dueLayout))
+	}
+	if !t.CompletedAt.IsZero() {
+		attributes = append(attributes, "done:"+t.CompletedAt.Format(dueLayout))
+	}
+	if t.Pomodoros > 0 {
+		attributes = append(attributes, fmt.Sprintf("pomos:%d", t.Pomodoros))
+	}
+	if t.FocusSeconds > 0 {
+		attributes = append(attributes, "spent:"+shortDuration(time.Duration(t.FocusSeconds)*time.Second))
+	}
+	if t.Source.File != "" && !strings.ContainsAny(t.Source.File, " :") {
+		attributes = append(attributes, "src:"+t.Source.String())
+	}
+	if t.Focus {
+		attributes = append(attributes, "focus:yes")
+	}
+	if t.Collapsed {
+		attributes = append(attributes, "fold:yes")
+	}
+
+	return marker + " [" + check + "] " + t.inputText() + " <!-- " + strings.Join(attributes, " ") + " -->"
+}
+
+func (s markdownTodoStore) read() ([]string, error) {
+	data, err := os.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+func (s markdownTodoStore) load() ([]TodoItem, error) {
+	lines, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	todos := []TodoItem{}
+	for i, item := range parseMarkdownTodos(lines, time.Now()) {
+		// The file is in manual order
+		item.todo.Order = i + 1
+		todos = append(todos, item.todo)
+	}
+	return todos, nil
+}
+
+// save writes the todos over the checklist items of the file. Todos that
+// are already in the file stay in the same checklist, and new ones go to
+// the checklist of the todo before them. Without any checklist, one is
+// added at the end of the file.
+func (s markdownTodoStore) save(todos []TodoItem) error {
+	lines, err := s.read()
+	if err != nil {
+		return err
+	}
+	items := parseMarkdownTodos(lines, time.Now())
+
+	// Items that had no ID yet are found by their text
+	blockOf := map[int]int{}
+	blocksOfText := map[string][]int{}
+	markers := map[int]string{}
+	unit := 0
+	for _, item := range items {
+		if item.todo.ID > 0 {
+			blockOf[item.todo.ID] = item.block
+		} else {
+			text := item.todo.inputText()
+			blocksOfText[text] = append(blocksOfText[text], item.block)
+		}
+		if _, ok := markers[item.block]; !ok {
+			markers[item.block] = item.marker
+		}
+		if item.indent > 0 && (unit == 0 || item.indent < unit) {
+			unit = item.indent
+		}
+	}
+	if unit == 0 {
+		unit = 2
+	}
+	if len(items) == 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "")
+		items = []markdownItem{{line: len(lines) - 1, block: 0}}
+		markers[0] = "-"
+	}
+
+	// Subtasks stay in the checklist of their top level todo
+	rendered := map[int][]string{}
+	block := 0
+	for _, todo := range arrangeTodos(todos, sortManual, true) {
+		b, ok := blockOf[todo.ID]
+		if blocks := blocksOfText[todo.inputText()]; !ok && len(blocks) > 0 {
+			b, ok = blocks[0], true
+			blocksOfText[todo.inputText()] = blocks[1:]
+		}
+		if ok && todo.depth == 0 {
+			block = b
+		}
+		indent := strings.Repeat(" ", unit*todo.depth)
+		rendered[block] = append(rendered[block], indent+todo.markdownItemText(markers[block]))
+	}
+
+	// Replace each checklist with its todos
+	var out []string
+	next := 0
+	for i, line := range lines {
+		if next < len(items) && items[next].line == i {
+			b := items[next].block
+			if next == 0 || items[next-1].block != b {
+				out = append(out, rendered[b]...)
+			}
+			next++
+			continue
+		}
+		out = append(out, line)
+	}
+
+	return os.WriteFile(s.filename, []byte(strings.Join(out, "\n")+"\n"), 0644)
+}
+
+func (s markdownTodoStore) modTime() time.Time { return fileModTime(s.filename) }
+func (s markdownTodoStore) name() string       { return filepath.Base(s.filename) }
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// markdownFile joins lines into the contents of a Markdown file.
+func markdownFile(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestMarkdownSave(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		change func(todos []TodoItem) []TodoItem
+		want   string
+	}{
+		{
+			name: "headings, text and code blocks stay",
+			file: markdownFile(
+				"# Project",
+				"",
+				"Some notes about the release.",
+				"",
+				"```markdown",
+				"- [ ] Not a todo",
+				"```",
+				"",
+				"- [ ] Write notes <!-- pom id:1 -->",
+				"",
+				"Closing words.",
+			),
+			change: func(todos []TodoItem) []TodoItem {
+				todos[0].Completed = true
+				return todos
+			},
+			want: markdownFile(
+				"# Project",
+				"",
+				"Some notes about the release.",
+				"",
+				"```markdown",
+				"- [ ] Not a todo",
+				"```",
+				"",
+				"- [x] Write notes <!-- pom id:1 -->",
+				"",
+				"Closing words.",
+			),
+		},
+		{
+			name: "subtasks stay under their parent",
+			file: markdownFile(
+				"- [ ] Release <!-- pom id:1 -->",
+				"    - [ ] Tag <!-- pom id:2 -->",
+				"        - [ ] Sign the tag <!-- pom id:3 -->",
+				"- [ ] Announce <!-- pom id:4 -->",
+			),
+			change: func(todos []TodoItem) []TodoItem {
+				// Moving the parent takes its subtasks along
+				return []TodoItem{todos[3], todos[0], todos[1], todos[2]}
+			},
+			want: markdownFile(
+				"- [ ] Announce <!-- pom id:4 -->",
+				"- [ ] Release <!-- pom id:1 -->",
+				"    - [ ] Tag <!-- pom id:2 -->",
+				"        - [ ] Sign the tag <!-- pom id:3 -->",
+			),
+		},
+		{
+			name: "new todos go to the checklist of the todo before them",
+			file: markdownFile(
+				"## Backend",
+				"",
+				"* [ ] Add caching <!-- pom id:1 -->",
+				"",
+				"## Frontend",
+				"",
+				"- [ ] Fix layout <!-- pom id:2 -->",
+			),
+			change: func(todos []TodoItem) []TodoItem {
+				return []TodoItem{
+					todos[0],
+					{ID: 3, Text: "Pick a library", ParentID: 1},
+					{ID: 4, Text: "Add metrics"},
+					todos[1],
+					{ID: 5, Text: "Dark mode"},
+				}
+			},
+			want: markdownFile(
+				"## Backend",
+				"",
+				"* [ ] Add caching <!-- pom id:1 -->",
+				"  * [ ] Pick a library <!-- pom id:3 -->",
+				"* [ ] Add metrics <!-- pom id:4 -->",
+				"",
+				"## Frontend",
+				"",
+				"- [ ] Fix layout <!-- pom id:2 -->",
+				"- [ ] Dark mode <!-- pom id:5 -->",
+			),
+		},
+		{
+			name: "a file without checklists gets one at the end",
+			file: markdownFile("# Notes"),
+			change: func(todos []TodoItem) []TodoItem {
+				return append(todos, TodoItem{ID: 1, Text: "First todo"})
+			},
+			want: markdownFile(
+				"# Notes",
+				"",
+				"- [ ] First todo <!-- pom id:1 -->",
+			),
+		},
+		{
+			name: "removed todos are removed from their checklist",
+			file: markdownFile(
+				"- [ ] Keep <!-- pom id:1 -->",
+				"- [ ] Drop <!-- pom id:2 -->",
+				"",
+				"Text after the list.",
+			),
+			change: func(todos []TodoItem) []TodoItem {
+				return todos[:1]
+			},
+			want: markdownFile(
+				"- [ ] Keep <!-- pom id:1 -->",
+				"",
+				"Text after the list.",
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := markdownTodoStore{filename: filepath.Join(t.TempDir(), "TODO.md")}
+			if err := os.WriteFile(store.filename, []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			todos, err := store.load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			todos = tt.change(todos)
+			for i := range todos {
+				todos[i].Order = i + 1
+			}
+			if err := store.save(todos); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(store.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(data); got != tt.want {
+				t.Errorf("saved\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// markdownModel returns a todo list kept in a Markdown file with the given
+// contents.
+func markdownModel(t *testing.T, contents string) (TodoModel, markdownTodoStore) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	store := markdownTodoStore{filename: filepath.Join(dir, "TODO.md")}
+	if err := os.WriteFile(store.filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewTodoModel()
+	m.store = store
+	m.stored = nil
+	m.loadTodos()
+	return m, store
+}
+
+func TestMarkdownHandAddedItemsKeepTheirIDs(t *testing.T) {
+	file := markdownFile(
+		"- [ ] Write tests <!-- pom id:7 -->",
+		"- [ ] Added by hand",
+		"  - [ ] Its subtask",
+	)
+	m, store := markdownModel(t, file)
+
+	byText := map[string]TodoItem{}
+	for _, todo := range m.todos {
+		byText[todo.Text] = todo
+	}
+	added, subtask := byText["Added by hand"], byText["Its subtask"]
+	if added.ID <= 7 || subtask.ID <= 7 || added.ID == subtask.ID || subtask.ParentID != added.ID {
+		t.Fatalf("hand-added items got IDs %d and %d (parent %d)", added.ID, subtask.ID, subtask.ParentID)
+	}
+
+	// Loading does not write the file
+	if data, _ := os.ReadFile(store.filename); string(data) != file {
+		t.Errorf("loading rewrote the file:\n%s", data)
+	}
+
+	// Reading the file again, as when it changes, gives the same IDs
+	m.loadTodos()
+	for _, todo := range m.todos {
+		if want := byText[todo.Text]; todo.ID != want.ID || todo.ParentID != want.ParentID {
+			t.Errorf("%q is now %d under %d, was %d under %d", todo.Text, todo.ID, todo.ParentID, want.ID, want.ParentID)
+		}
+	}
+
+	// The IDs are written with the next change and read back as they are
+	m.toggleTodo(m.findTodo(7))
+	reloaded, err := store.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, todo := range reloaded {
+		if want := byText[todo.Text]; todo.ID != want.ID || todo.ParentID != want.ParentID {
+			t.Errorf("%q was saved as %d under %d, want %d under %d", todo.Text, todo.ID, todo.ParentID, want.ID, want.ParentID)
+		}
+	}
+}
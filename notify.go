@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/godbus/dbus/v5"
+)
+
+// NotificationConfig controls the notifications sent when a session runs
+// out. Notifications go through the freedesktop notification service on
+// the D-Bus session bus; if that is not available the fallback is written
+// to the terminal instead.
+type NotificationConfig struct {
+	Enabled    bool   `toml:"enabled"`
+	Fallback   string `toml:"fallback"`
+	Work       bool   `toml:"work"`
+	ShortBreak bool   `toml:"short_break"`
+	LongBreak  bool   `toml:"long_break"`
+}
+
+var notificationFallbacks = []string{"bell", "osc9", "osc777", "none"}
+
+func (c NotificationConfig) validate() error {
+	for _, fallback := range notificationFallbacks {
+		if c.Fallback == fallback {
+			return nil
+		}
+	}
+	return fmt.Errorf("notifications.fallback must be one of bell, osc9, osc777 or none, got %q", c.Fallback)
+}
+
+type Notifier struct {
+	enabled  map[sessionType]bool
+	fallback string
+}
+
+func NewNotifier(cfg NotificationConfig) Notifier {
+	return Notifier{
+		enabled: map[sessionType]bool{
+			work:       cfg.Enabled && cfg.Work,
+			shortBreak: cfg.Enabled && cfg.ShortBreak,
+			longBreak:  cfg.Enabled && cfg.LongBreak,
+		},
+		fallback: cfg.Fallback,
+	}
+}
+
+// sessionEnded returns a command announcing that a session of type ended
+// ran out and that next is up.
+func (n Notifier) sessionEnded(ended sessionType, next TimerModel) tea.Cmd {
+	if !n.enabled[ended] {
+		return nil
+	}
+
+	finished := TimerModel{sessionType: ended}
+	summary := fmt.Sprintf("%s %s finished", finished.getSessionEmoji(), finished.getSessionName())
+	body := fmt.Sprintf("Next: %s (%s)", next.getSessionName(), shortDuration(next.getCurrentSessionDuration()))
+
+	return func() tea.Msg {
+		return n.send(summary, body)
+	}
+}
+
+// send sends a notification over D-Bus. If that fails it returns the
+// terminal fallback, which must be written by whoever owns the terminal.
+func (n Notifier) send(summary, body string) tea.Msg {
+	conn, err := dbus.ConnectSessionBus()
+	if err == nil {
+		defer conn.Close()
+		if err = sendDBusNotification(conn, summary, body); err == nil {
+			return nil
+		}
+	}
+	if seq := n.terminalNotification(summary, body); seq != "" {
+		return terminalNotificationMsg{seq}
+	}
+	return nil
+}
+
+// sendDBusNotification calls org.freedesktop.Notifications.Notify on the
+// given bus connection.
+func sendDBusNotification(conn *dbus.Conn, summary, body string) error {
+	obj := conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	call := obj.Call("org.freedesktop.Notifications.Notify", 0,
+		"pom",                     // app_name
+		uint32(0),                 // replaces_id
+		"",                        // app_icon
+		summary,                   // summary
+		body,                      // body
+		[]string{},                // actions
+		map[string]dbus.Variant{}, // hints
+		int32(-1),                 // expire_timeout: server default
+	)
+	return call.Err
+}
+
+// terminalNotification returns the fallback: a plain bell, or an OSC 9
+// (iTerm2, Windows Terminal, kitty) or OSC 777 (urxvt, foot, VTE)
+// notification sequence, both followed by a bell for terminals that ignore
+// them.
+func (n Notifier) terminalNotification(summary, body string) string {
+	switch n.fallback {
+	case "bell":
+		return "\a"
+	case "osc9":
+		return fmt.Sprintf("\x1b]9;%s: %s\x07\a", summary, body)
+	case "osc777":
+		return fmt.Sprintf("\x1b]777;notify;%s;%s\x07\a", summary, body)
+	}
+	return ""
+}
+
+// terminalNotificationMsg carries a terminal notification sequence. The UI
+// renders it as part of a frame, so that it does not interfere with the
+// renderer writing to the same terminal.
+type terminalNotificationMsg struct {
+	seq string
+}
+
+// terminalNotificationClearMsg takes a rendered notification sequence out
+// of the frame again, unless a newer one replaced it.
+type terminalNotificationClearMsg struct {
+	id int
+}
+
+// terminalNotificationTime is how long a notification sequence stays in the
+// frame. The renderer writes it once, as it only redraws changed lines.
+const terminalNotificationTime = time.Second
+
+func clearTerminalNotification(id int) tea.Cmd {
+	return tea.Tick(terminalNotificationTime, func(time.Time) tea.Msg {
+		return terminalNotificationClearMsg{id}
+	})
+}
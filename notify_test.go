@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeNotifications records the notifications sent to it.
+type fakeNotifications struct {
+	notes chan [2]string
+}
+
+func (f fakeNotifications) Notify(app string, replaces uint32, icon, summary, body string,
+	actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	f.notes <- [2]string{summary, body}
+	return 1, nil
+}
+
+// privateBus starts a session bus of its own for the test and returns its
+// address.
+func privateBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon not found")
+	}
+
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("could not start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func connectBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connecting to bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSendDBusNotification(t *testing.T) {
+	address := privateBus(t)
+
+	server := connectBus(t, address)
+	fake := fakeNotifications{notes: make(chan [2]string, 1)}
+	if err := server.Export(fake, "/org/freedesktop/Notifications", "org.freedesktop.Notifications"); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := server.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("could not own the notifications name: %v", err)
+	}
+
+	client := connectBus(t, address)
+	if err := sendDBusNotification(client, "Work session completed", "Time for a break"); err != nil {
+		t.Fatalf("sendDBusNotification: %v", err)
+	}
+
+	got := <-fake.notes
+	if got != [2]string{"Work session completed", "Time for a break"} {
+		t.Errorf("got notification %q, want summary and body as sent", got)
+	}
+}
+
+func TestSendDBusNotificationWithoutServer(t *testing.T) {
+	client := connectBus(t, privateBus(t))
+	if err := sendDBusNotification(client, "Work session completed", "Time for a break"); err == nil {
+		t.Error("expected an error when no notification server is running")
+	}
+}
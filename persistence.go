@@ -24,7 +24,7 @@ func getDataDir() (string, error) {
 	return dataDir, nil
 }
 
-func getSessionFilename() (string, error) {
+func getSessionID() (string, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return "", err
@@ -32,14 +32,35 @@ func getSessionFilename() (string, error) {
 	
 	// Create a hash of the current working directory
 	hash := md5.Sum([]byte(cwd))
-	sessionID := hex.EncodeToString(hash[:])
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// getSessionFile returns the path of a per-directory data file, named after
+// the hash of the current working directory plus the given suffix.
+func getSessionFile(suffix string) (string, error) {
+	sessionID, err := getSessionID()
+	if err != nil {
+		return "", err
+	}
 	
 	dataDir, err := getDataDir()
 	if err != nil {
 		return "", err
 	}
 	
-	return filepath.Join(dataDir, sessionID+".json"), nil
+	return filepath.Join(dataDir, sessionID+suffix), nil
+}
+
+func getSessionFilename() (string, error) {
+	return getSessionFile(".json")
+}
+
+func getTimerStateFilename() (string, error) {
+	return getSessionFile(".timer.json")
+}
+
+func getArchiveFilename() (string, error) {
+	return getSessionFile(".archive.json")
 }
 
 func saveTodosToFile(todos []TodoItem) error {
@@ -47,21 +68,43 @@ func saveTodosToFile(todos []TodoItem) error {
 	if err != nil {
 		return err
 	}
-	
-	data, err := json.MarshalIndent(todos, "", "  ")
+	return writeTodoFile(filename, todos)
+}
+
+func loadTodosFromFile() ([]TodoItem, error) {
+	filename, err := getSessionFilename()
+	if err != nil {
+		return nil, err
+	}
+	return readTodoFile(filename)
+}
+
+func saveArchiveToFile(todos []TodoItem) error {
+	filename, err := getArchiveFilename()
 	if err != nil {
 		return err
 	}
-	
-	return ioutil.WriteFile(filename, data, 0644)
+	return writeTodoFile(filename, todos)
 }
 
-func loadTodosFromFile() ([]TodoItem, error) {
-	filename, err := getSessionFilename()
+func loadArchiveFromFile() ([]TodoItem, error) {
+	filename, err := getArchiveFilename()
 	if err != nil {
 		return nil, err
 	}
+	return readTodoFile(filename)
+}
+
+func writeTodoFile(filename string, todos []TodoItem) error {
+	data, err := json.MarshalIndent(todos, "", "  ")
+	if err != nil {
+		return err
+	}
 	
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+func readTodoFile(filename string) ([]TodoItem, error) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		// File doesn't exist, return empty slice
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A recurrence rule says when a recurring todo comes back after it is
+// completed: "daily", "weekdays", "weekly", a list of weekdays such as
+// "mon,thu", or every N days or weeks such as "3d" or "2w".
+func parseRecurrence(rule string) (string, bool) {
+	rule = strings.ToLower(rule)
+	switch rule {
+	case "daily", "weekdays", "weekly":
+		return rule, true
+	}
+
+	if n, unit, ok := parseInterval(rule); ok {
+		return fmt.Sprintf("%d%c", n, unit), true
+	}
+
+	var days []string
+	for _, name := range strings.Split(rule, ",") {
+		day, ok := parseWeekday(name)
+		if !ok {
+			return "", false
+		}
+		days = append(days, strings.ToLower(day.String()[:3]))
+	}
+	return strings.Join(days, ","), true
+}
+
+// parseInterval parses "3d" or "2w".
+func parseInterval(rule string) (int, byte, bool) {
+	if len(rule) < 2 {
+		return 0, 0, false
+	}
+	unit := rule[len(rule)-1]
+	n, err := strconv.Atoi(rule[:len(rule)-1])
+	if err != nil || n < 1 || n > 365 || (unit != 'd' && unit != 'w') {
+		return 0, 0, false
+	}
+	return n, unit, true
+}
+
+// nextOccurrence returns the first day after the given day on which a
+// todo with the rule comes back.
+func nextOccurrence(rule string, after time.Time) time.Time {
+	day := startOfDay(after)
+	switch rule {
+	case "daily":
+		return day.AddDate(0, 0, 1)
+	case "weekly":
+		return day.AddDate(0, 0, 7)
+	case "weekdays":
+		rule = "mon,tue,wed,thu,fri"
+	}
+
+	if n, unit, ok := parseInterval(rule); ok {
+		if unit == 'w' {
+			n *= 7
+		}
+		return day.AddDate(0, 0, n)
+	}
+
+	days := map[time.Weekday]bool{}
+	for _, name := range strings.Split(rule, ",") {
+		if weekday, ok := parseWeekday(name); ok {
+			days[weekday] = true
+		}
+	}
+	for i := 1; i <= 7; i++ {
+		next := day.AddDate(0, 0, i)
+		if days[next.Weekday()] {
+			return next
+		}
+	}
+	return day.AddDate(0, 0, 1)
+}
+
+// scheduled reports whether the todo is waiting for a later day before it
+// shows up in the list.
+func (t TodoItem) scheduled(now time.Time) bool {
+	return t.Scheduled != "" && t.Scheduled > startOfDay(now).Format(dueLayout)
+}
+
+// nextRecurrence returns the next occurrence of a completed recurring
+// todo, to be shown from the day it is due again. A todo completed ahead
+// of its due date recurs counting from the due date.
+func (t TodoItem) nextRecurrence(id int, now time.Time) TodoItem {
+	after := startOfDay(now)
+	if due, ok := t.dueDate(); ok && due.After(after) {
+		after = due
+	}
+	date := nextOccurrence(t.Recur, after).Format(dueLayout)
+
+	next := TodoItem{
+		Text:      t.Text,
+		ID:        id,
+		Estimate:  t.Estimate,
+		Priority:  t.Priority,
+		CreatedAt: now,
+		Projects:  t.Projects,
+		Contexts:  t.Contexts,
+		ParentID:  t.ParentID,
+		Recur:     t.Recur,
+		Scheduled: date,
+	}
+	if t.Due != "" {
+		next.Due = date
+	}
+	return next
+}
+
+// pendingRecurrence returns the index of the open occurrence of a
+// recurring todo other than the todo itself, or -1 if there is none.
+func (m TodoModel) pendingRecurrence(todo TodoItem) int {
+	for i, t := range m.todos {
+		if t.ID != todo.ID && !t.Completed && t.Recur == todo.Recur &&
+			t.Text == todo.Text && t.ParentID == todo.ParentID {
+			return i
+		}
+	}
+	return -1
+}
+
+// untouchedRecurrence reports whether the todo at index is an occurrence
+// that has not come up yet and was not worked on or given subtasks, so it
+// can be taken back.
+func (m TodoModel) untouchedRecurrence(index int, now time.Time) bool {
+	t := m.todos[index]
+	if !t.scheduled(now) || t.Pomodoros > 0 || t.FocusSeconds > 0 {
+		return false
+	}
+	for _, todo := range m.todos {
+		if todo.ParentID == t.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// materializeRecurrences shows the todos whose scheduled day has come. It
+// returns whether any did.
+func (m *TodoModel) materializeRecurrences(now time.Time) bool {
+	changed := false
+	for i, todo := range m.todos {
+		if todo.Scheduled != "" && !todo.scheduled(now) {
+			m.todos[i].Scheduled = ""
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type periodStats struct {
+	focus         time.Duration
+	pomodoros     int
+	workSessions  int
+	interruptions int
+}
+
+func (p periodStats) averageSession() time.Duration {
+	if p.workSessions == 0 {
+		return 0
+	}
+	return p.focus / time.Duration(p.workSessions)
+}
+
+type StatsModel struct {
+	history []SessionRecord
+	todos   []TodoItem
+	err     error
+	now     time.Time
+}
+
+func NewStatsModel() StatsModel {
+	m := StatsModel{}
+	m.Refresh()
+	return m
+}
+
+// Refresh reloads the session history and the todos so the view reflects
+// sessions recorded since the stats were last shown. Archived todos count
+// towards the estimates as well.
+func (m *StatsModel) Refresh() {
+	m.history, m.err = loadSessionHistory()
+	m.todos, _ = openTodoStore().load()
+	archive, _ := loadArchiveFromFile()
+	m.todos = append(m.todos, archive...)
+	m.now = time.Now()
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, mo, d := t.Date()
+	return time.Date(y, mo, d, 0, 0, 0, 0, t.Location())
+}
+
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	// Weeks start on Monday
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func startOfMonth(t time.Time) time.Time {
+	y, mo, _ := t.Date()
+	return time.Date(y, mo, 1, 0, 0, 0, 0, t.Location())
+}
+
+func (m StatsModel) statsSince(since time.Time) periodStats {
+	var p periodStats
+	for _, record := range m.history {
+		if record.Type != work.String() || record.StartedAt.Before(since) {
+			continue
+		}
+
+		p.workSessions++
+		p.focus += time.Duration(record.ElapsedSeconds) * time.Second
+		p.interruptions += record.Pauses
+		if record.Completed {
+			p.pomodoros++
+		} else {
+			p.interruptions++
+		}
+	}
+	return p
+}
+
+// streak returns the number of consecutive days, ending today, with at
+// least one completed pomodoro. A day without pomodoros yet today does not
+// break a streak that ran until yesterday.
+func (m StatsModel) streak() int {
+	days := map[time.Time]bool{}
+	for _, record := range m.history {
+		if record.Type == work.String() && record.Completed {
+			days[startOfDay(record.StartedAt.In(m.now.Location()))] = true
+		}
+	}
+
+	day := startOfDay(m.now)
+	if !days[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	streak := 0
+	for days[day] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// estimateStats compares estimates with the pomodoros completed tasks
+// actually took.
+type estimateStats struct {
+	tasks     int
+	estimated int
+	actual    int
+	onTarget  int
+	over      int
+	under     int
+}
+
+func estimateAccuracy(todos []TodoItem) estimateStats {
+	var s estimateStats
+	for _, todo := range todos {
+		if !todo.Completed || todo.Estimate == 0 {
+			continue
+		}
+
+		s.tasks++
+		s.estimated += todo.Estimate
+		s.actual += todo.Pomodoros
+		switch {
+		case todo.Pomodoros > todo.Estimate:
+			s.over++
+		case todo.Pomodoros < todo.Estimate:
+			s.under++
+		default:
+			s.onTarget++
+		}
+	}
+	return s
+}
+
+func (s estimateStats) String() string {
+	if s.tasks == 0 {
+		return "No completed tasks with estimates yet"
+	}
+
+	tasks := "tasks"
+	if s.tasks == 1 {
+		tasks = "task"
+	}
+	return fmt.Sprintf("🎯 Estimates: %d 🍅 planned, %d used over %d %s (%d%%)\nOn target: %d • Over: %d • Under: %d",
+		s.estimated, s.actual, s.tasks, tasks, s.actual*100/s.estimated, s.onTarget, s.over, s.under)
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	if hours > 0 {
+		return fmt.Sprintf("%dh %02dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+func (m StatsModel) View() string {
+	width := 60 // Fixed width for consistent centering
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		Align(lipgloss.Center).
+		MarginBottom(1).
+		Width(width)
+
+	tableStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Padding(1, 2).
+		Width(width)
+
+	streakStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("99")).
+		MarginTop(1).
+		Align(lipgloss.Center).
+		Width(width)
+
+	title := titleStyle.Render("📊 Statistics")
+
+	if m.err != nil {
+		return lipgloss.JoinVertical(
+			lipgloss.Center,
+			title,
+			fmt.Sprintf("Could not read session history: %v", m.err),
+		)
+	}
+
+	periods := []periodStats{
+		m.statsSince(startOfDay(m.now)),
+		m.statsSince(startOfWeek(m.now)),
+		m.statsSince(startOfMonth(m.now)),
+	}
+
+	rows := []struct {
+		label string
+		value func(p periodStats) string
+	}{
+		{"Focus time", func(p periodStats) string { return formatDuration(p.focus) }},
+		{"Pomodoros", func(p periodStats) string { return fmt.Sprintf("%d", p.pomodoros) }},
+		{"Avg session", func(p periodStats) string { return formatDuration(p.averageSession()) }},
+		{"Interruptions", func(p periodStats) string { return fmt.Sprintf("%d", p.interruptions) }},
+	}
+
+	var table strings.Builder
+	table.WriteString(fmt.Sprintf("%-14s%10s%10s%10s", "", "Today", "Week", "Month"))
+	for _, row := range rows {
+		table.WriteString(fmt.Sprintf("\n%-14s", row.label))
+		for _, p := range periods {
+			table.WriteString(fmt.Sprintf("%10s", row.value(p)))
+		}
+	}
+
+	streak := m.streak()
+	streakText := "No current streak"
+	if streak == 1 {
+		streakText = "🔥 Streak: 1 day"
+	} else if streak > 1 {
+		streakText = fmt.Sprintf("🔥 Streak: %d days", streak)
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Center,
+		title,
+		tableStyle.Render(table.String()),
+		streakStyle.Render(streakText),
+		streakStyle.Render(estimateAccuracy(m.todos).String()),
+	)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// statusLineData is what status line templates can refer to.
+type statusLineData struct {
+	Emoji            string
+	Name             string
+	Session          string
+	Remaining        string
+	RemainingSeconds int
+	State            string
+	Running          bool
+	Sessions         int
+	Task             string
+}
+
+var statusLineTemplates = map[string]string{
+	"plain":  `{{if not .Running}}⏸ {{end}}{{.Emoji}} {{.Remaining}}{{if .Task}} {{.Task}}{{end}}`,
+	"tmux":   `#[fg={{if not .Running}}colour241{{else if eq .Session "work"}}colour205{{else}}colour114{{end}}]{{.Emoji}} {{.Remaining}}#[default]{{if .Task}} {{.Task}}{{end}}`,
+	"waybar": `{{.Emoji}} {{.Remaining}}`,
+}
+
+// waybarOutput is the JSON object understood by waybar's custom modules.
+type waybarOutput struct {
+	Text    string   `json:"text"`
+	Alt     string   `json:"alt"`
+	Tooltip string   `json:"tooltip"`
+	Class   []string `json:"class"`
+}
+
+// liveTimerStatus returns the status of the daemon if one is running, and
+// the saved state of the current directory otherwise. Unlike the timer
+// commands it never writes anything, since status bars poll it constantly.
+func liveTimerStatus(timer TimerModel) (TimerStatus, error) {
+	if daemonAvailable() {
+		return sendDaemonCommand("status")
+	}
+
+	state, err := loadTimerState()
+	if err != nil {
+		return TimerStatus{}, err
+	}
+
+	timer.readOnly = true
+	if state != nil {
+		timer, _ = timer.restore(*state)
+	}
+	return timer.Status(), nil
+}
+
+// currentTask returns the text of the focus todo in the current directory,
+// or of the first open todo if none is focused.
+func currentTask() string {
+	todos, err := openTodoStore().load()
+	if err != nil {
+		return ""
+	}
+	if i := focusIndex(todos); i >= 0 {
+		return todos[i].Text
+	}
+	for _, todo := range todos {
+		if !todo.Completed && !todo.scheduled(time.Now()) {
+			return todo.Text
+		}
+	}
+	return ""
+}
+
+func newStatusLineData(status TimerStatus, task string) statusLineData {
+	state := "paused"
+	if status.Running {
+		state = "running"
+	} else if !status.Started {
+		state = "waiting"
+	}
+
+	return statusLineData{
+		Emoji:            status.Emoji,
+		Name:             status.Name,
+		Session:          status.Session,
+		Remaining:        formatClock(status.Remaining),
+		RemainingSeconds: status.Remaining,
+		State:            state,
+		Running:          status.Running,
+		Sessions:         status.SessionCount,
+		Task:             task,
+	}
+}
+
+func runStatusLineCommand(args []string) {
+	fs := flag.NewFlagSet("pom statusline", flag.ExitOnError)
+	cfg := mustLoadConfig()
+	newTimer := addTimerFlags(fs, &cfg)
+	formatFlag := fs.String("format", "plain", "Output format: plain, tmux or waybar")
+	templateFlag := fs.String("template", "", "Custom text/template for the output text")
+	fs.Parse(args)
+
+	text, ok := statusLineTemplates[*formatFlag]
+	if !ok {
+		fmt.Printf("Error: unknown format %q (expected plain, tmux or waybar)\n", *formatFlag)
+		os.Exit(1)
+	}
+	if *templateFlag != "" {
+		text = *templateFlag
+	}
+
+	tmpl, err := template.New("statusline").Parse(text)
+	if err != nil {
+		fmt.Printf("Error parsing template: %v\n", err)
+		os.Exit(1)
+	}
+
+	status, err := liveTimerStatus(newTimer())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	data := newStatusLineData(status, currentTask())
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		fmt.Printf("Error rendering template: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *formatFlag != "waybar" {
+		fmt.Println(out.String())
+		return
+	}
+
+	tooltip := fmt.Sprintf("%s — %s\nSessions: %d", data.Name, data.State, data.Sessions)
+	if data.Task != "" {
+		tooltip += "\nTask: " + data.Task
+	}
+	encoded, _ := json.Marshal(waybarOutput{
+		Text:    out.String(),
+		Alt:     data.Session,
+		Tooltip: tooltip,
+		Class:   []string{data.Session, data.State},
+	})
+	fmt.Println(string(encoded))
+}
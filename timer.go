@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -19,16 +20,61 @@ const (
 	longBreak
 )
 
+// String returns the identifier used for the session type in stored data.
+func (s sessionType) String() string {
+	switch s {
+	case shortBreak:
+		return "short_break"
+	case longBreak:
+		return "long_break"
+	default:
+		return "work"
+	}
+}
+
 type TimerModel struct {
 	timer               timer.Model
 	sessionType         sessionType
 	sessionCount        int
 	keys                TimerKeyMap
 	isRunning           bool
-	customDuration      *time.Duration
-	customShortBreak    *time.Duration
-	customLongBreak     *time.Duration
+	cycle               []segment
+	cycleIndex          int
 	progressLines       int
+	gradientStart       string
+	gradientEnd         string
+	toBreakPolicy       startPolicy
+	toWorkPolicy        startPolicy
+	pendingConfirm      bool
+	notifier            Notifier
+	hooks               Hooks
+	webhooks            Webhooks
+	warning             error
+	warningID           int
+	sessionStart        time.Time
+	elapsed             time.Duration
+	pauses              int
+	pendingResume       *timerState
+	suspendPolicy       suspendPolicy
+	deadline            time.Time
+	lastTick            time.Time
+	pendingGap          time.Duration
+	remote              bool
+	remoteErr           error
+	readOnly            bool
+}
+
+// timerWarningMsg reports a failure that should not stop the timer, such
+// as a hook that failed or a session that could not be written to the
+// history.
+type timerWarningMsg struct {
+	err error
+}
+
+// timerWarningClearMsg hides a warning again, unless a newer one has been
+// reported since.
+type timerWarningClearMsg struct {
+	id int
 }
 
 type TimerKeyMap struct {
@@ -38,19 +84,14 @@ type TimerKeyMap struct {
 }
 
 func DefaultTimerKeys() TimerKeyMap {
+	return TimerKeyMapFromConfig(DefaultConfig().Keys)
+}
+
+func TimerKeyMapFromConfig(cfg KeyConfig) TimerKeyMap {
 	return TimerKeyMap{
-		Start: key.NewBinding(
-			key.WithKeys(" "),
-			key.WithHelp("space", "start/pause"),
-		),
-		Reset: key.NewBinding(
-			key.WithKeys("r"),
-			key.WithHelp("r", "reset"),
-		),
-		End: key.NewBinding(
-			key.WithKeys("e"),
-			key.WithHelp("e", "end session"),
-		),
+		Start: newConfiguredBinding(cfg.Start, "start/pause"),
+		Reset: newConfiguredBinding(cfg.Reset, "reset"),
+		End:   newConfiguredBinding(cfg.End, "end session"),
 	}
 }
 
@@ -67,56 +108,167 @@ func NewTimerModelWithDurations(sessionDuration, shortBreakDuration, longBreakDu
 }
 
 func NewTimerModelWithOptions(sessionDuration, shortBreakDuration, longBreakDuration time.Duration, lines int) TimerModel {
+	cfg := DefaultConfig()
+	cfg.Work.Duration = sessionDuration
+	cfg.ShortBreak.Duration = shortBreakDuration
+	cfg.LongBreak.Duration = longBreakDuration
+	cfg.ProgressLines = lines
+	return NewTimerModelWithConfig(cfg)
+}
+
+// NewTimerModelWithConfig creates a timer from a validated configuration.
+func NewTimerModelWithConfig(cfg Config) TimerModel {
+	cycle, _ := cfg.resolveCycle()
+	policy, _ := parseSuspendPolicy(cfg.Suspend)
+	toBreak, toWork, _ := cfg.startPolicies()
+
 	return TimerModel{
-		timer:            timer.NewWithInterval(sessionDuration, time.Second),
-		sessionType:      work,
-		sessionCount:     0,
-		keys:             DefaultTimerKeys(),
-		isRunning:        false,
-		customDuration:   &sessionDuration,
-		customShortBreak: &shortBreakDuration,
-		customLongBreak:  &longBreakDuration,
-		progressLines:    lines,
+		timer:         timer.NewWithInterval(cycle[0].duration, time.Second),
+		sessionType:   cycle[0].kind,
+		sessionCount:  0,
+		keys:          TimerKeyMapFromConfig(cfg.Keys),
+		isRunning:     false,
+		cycle:         cycle,
+		cycleIndex:    0,
+		progressLines: cfg.ProgressLines,
+		gradientStart: cfg.Colors.GradientStart,
+		gradientEnd:   cfg.Colors.GradientEnd,
+		toBreakPolicy: toBreak,
+		toWorkPolicy:  toWork,
+		notifier:      NewNotifier(cfg.Notifications),
+		hooks:         NewHooks(cfg.Hooks),
+		webhooks:      NewWebhooks(cfg.Webhooks),
+		suspendPolicy: policy,
 	}
 }
 
 func (m TimerModel) Init() tea.Cmd {
+	if m.remote {
+		return tea.Batch(daemonCommand("status"), pollDaemon())
+	}
 	return nil
 }
 
 func (m TimerModel) Update(msg tea.Msg) (TimerModel, tea.Cmd) {
+	if m.remote {
+		return m.updateRemote(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case " ":
-			if m.isRunning {
-				m.isRunning = false
-				return m, m.timer.Stop()
-			} else {
-				m.isRunning = true
-				return m, m.timer.Start()
+		if m.pendingResume != nil {
+			switch msg.String() {
+			case "y":
+				var recorded tea.Cmd
+				m, recorded = m.restore(*m.pendingResume)
+				m.pendingResume = nil
+				var cmd tea.Cmd
+				if m.isRunning {
+					cmd = m.startCountdown()
+				}
+				m.saveState()
+				return m, tea.Batch(recorded, cmd)
+			case "n":
+				m.pendingResume = nil
+				m.saveState()
 			}
-		case "r":
-			m.isRunning = false
-			duration := m.getCurrentSessionDuration()
-			m.timer = timer.NewWithInterval(duration, time.Second)
 			return m, nil
-		case "e":
-			m.isRunning = false
-			newModel := m.nextSession()
-			return newModel, nil
+		}
+
+		if m.pendingConfirm {
+			switch msg.String() {
+			case "y":
+				return m.start()
+			case "n":
+				return m.skip()
+			}
+			if !key.Matches(msg, m.keys.Start) {
+				return m, nil
+			}
+		}
+
+		if m.pendingGap > 0 {
+			switch msg.String() {
+			case "y":
+				m.setRemaining(m.timer.Timeout - m.pendingGap)
+				m.pendingGap = 0
+				cmd := m.startCountdown()
+				m.saveState()
+				return m, tea.Batch(cmd, m.emit(m.event("resume")))
+			case "n":
+				m.pendingGap = 0
+				cmd := m.startCountdown()
+				m.saveState()
+				return m, tea.Batch(cmd, m.emit(m.event("resume")))
+			}
+			return m, nil
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Start):
+			if m.isRunning {
+				return m.pause()
+			}
+			return m.start()
+		case key.Matches(msg, m.keys.Reset):
+			return m.reset()
+		case key.Matches(msg, m.keys.End):
+			return m.end()
 		}
 	case timer.TickMsg:
-		if m.isRunning {
-			var cmd tea.Cmd
-			m.timer, cmd = m.timer.Update(msg)
-			return m, cmd
+		if !m.isRunning || msg.ID != m.timer.ID() {
+			return m, nil
+		}
+
+		now := wallClock()
+		if gap := now.Sub(m.lastTick); gap > suspendThreshold {
+			switch m.suspendPolicy {
+			case suspendPause:
+				cmd := m.pauseCountdown(m.lastTick)
+				m.pauses++
+				m.saveState()
+				return m, tea.Batch(cmd, m.emit(m.event("pause")))
+			case suspendAsk:
+				cmd := m.pauseCountdown(m.lastTick)
+				m.pauses++
+				m.pendingGap = gap
+				m.saveState()
+				return m, tea.Batch(cmd, m.emit(m.event("pause")))
+			}
+		}
+		m.lastTick = now
+
+		// The bubbles timer counts down one interval per tick. Hand it the
+		// remaining time according to the deadline plus that interval, so
+		// the displayed time follows the wall clock and its own timeout
+		// detection fires once the deadline has passed.
+		before := m.timer.Timeout
+		m.timer.Timeout = m.deadline.Sub(now).Round(time.Second) + m.timer.Interval
+		var cmd tea.Cmd
+		m.timer, cmd = m.timer.Update(msg)
+		m.elapsed += before - m.timer.Timeout
+		return m, cmd
+	case timerWarningMsg:
+		m.warning = msg.err
+		m.warningID++
+		id := m.warningID
+		return m, tea.Tick(15*time.Second, func(time.Time) tea.Msg {
+			return timerWarningClearMsg{id}
+		})
+	case timerWarningClearMsg:
+		if msg.id == m.warningID {
+			m.warning = nil
 		}
 		return m, nil
 	case timer.TimeoutMsg:
-		m.isRunning = true
-		newModel := m.nextSession()
-		return newModel, newModel.timer.Start()
+		if msg.ID != m.timer.ID() {
+			return m, nil
+		}
+		recorded := m.recordSession(true, time.Now())
+		ended := m.event("end")
+		ended.Completed = true
+		newModel, cmd := m.nextSession().beginSession(ended)
+		return newModel, tea.Batch(recorded, cmd, m.notifier.sessionEnded(m.sessionType, newModel))
 	}
 
 	var cmd tea.Cmd
@@ -124,6 +276,122 @@ func (m TimerModel) Update(msg tea.Msg) (TimerModel, tea.Cmd) {
 	return m, cmd
 }
 
+// skip skips the session waiting to be confirmed and begins the one after
+// it.
+func (m TimerModel) skip() (TimerModel, tea.Cmd) {
+	skipped := m.event("skip")
+	return m.nextSession().beginSession(skipped)
+}
+
+// start starts or resumes the current session.
+func (m TimerModel) start() (TimerModel, tea.Cmd) {
+	if m.isRunning {
+		return m, nil
+	}
+	action := "resume"
+	if m.sessionStart.IsZero() {
+		m.sessionStart = time.Now()
+		action = "start"
+	}
+	m.pendingConfirm = false
+	cmd := m.startCountdown()
+	m.saveState()
+	return m, tea.Batch(cmd, m.emit(m.event(action)))
+}
+
+// pause pauses the current session.
+func (m TimerModel) pause() (TimerModel, tea.Cmd) {
+	if !m.isRunning {
+		return m, nil
+	}
+	cmd := m.pauseCountdown(wallClock())
+	m.pauses++
+	m.saveState()
+	return m, tea.Batch(cmd, m.emit(m.event("pause")))
+}
+
+// reset stops the current session and sets it back to its full duration.
+func (m TimerModel) reset() (TimerModel, tea.Cmd) {
+	if m.isRunning {
+		m.setRemaining(m.deadline.Sub(wallClock()))
+	}
+	m.isRunning = false
+	duration := m.getCurrentSessionDuration()
+	m.timer = timer.NewWithInterval(duration, time.Second)
+	// The session starts over, so nothing of it is recorded
+	m.sessionStart = time.Time{}
+	m.elapsed = 0
+	m.pauses = 0
+	m.saveState()
+	return m, nil
+}
+
+// end cuts the current session short and moves on to the next one.
+func (m TimerModel) end() (TimerModel, tea.Cmd) {
+	if m.isRunning {
+		m.setRemaining(m.deadline.Sub(wallClock()))
+	}
+	m.isRunning = false
+	recorded := m.recordSession(false, time.Now())
+	newModel := m.nextSession()
+	newModel.saveState()
+	return newModel, tea.Batch(recorded, m.emit(m.event("end")))
+}
+
+// startPolicyFor returns the policy for reaching a session of the given
+// type at the end of the previous one.
+func (m TimerModel) startPolicyFor(kind sessionType) startPolicy {
+	if kind == work {
+		return m.toWorkPolicy
+	}
+	return m.toBreakPolicy
+}
+
+// beginSession applies the start policy to the session that was just
+// reached because the previous one ran out. Any events given are emitted
+// first, followed by the start of the session if it starts right away.
+func (m TimerModel) beginSession(events ...timerEvent) (TimerModel, tea.Cmd) {
+	switch m.startPolicyFor(m.sessionType) {
+	case startAuto:
+		m.sessionStart = time.Now()
+		cmd := m.startCountdown()
+		m.saveState()
+		events = append(events, m.event("start"))
+		return m, tea.Batch(cmd, m.emit(events...))
+	case startConfirm:
+		m.pendingConfirm = true
+	}
+	m.isRunning = false
+	m.saveState()
+	return m, m.emit(events...)
+}
+
+// startCountdown starts (or resumes) the current session, fixing its
+// deadline on the wall clock.
+func (m *TimerModel) startCountdown() tea.Cmd {
+	now := wallClock()
+	m.isRunning = true
+	m.deadline = now.Add(m.timer.Timeout)
+	m.lastTick = now
+	return m.timer.Start()
+}
+
+// pauseCountdown stops the current session with the time that was left at
+// the given moment.
+func (m *TimerModel) pauseCountdown(at time.Time) tea.Cmd {
+	m.setRemaining(m.deadline.Sub(at))
+	m.isRunning = false
+	return m.timer.Stop()
+}
+
+// setRemaining sets the remaining time of the current session, counting any
+// difference to the previous remaining time as elapsed.
+func (m *TimerModel) setRemaining(remaining time.Duration) {
+	remaining = remaining.Round(time.Second)
+	m.elapsed += m.timer.Timeout - remaining
+	m.timer.Timeout = remaining
+}
+
 func (m TimerModel) IsRunning() bool {
 	return m.isRunning && !m.timer.Timedout()
 }
@@ -163,7 +431,7 @@ func (m *TimerModel) getSandTimer(width int) string {
 			
 			// Calculate gradient color based on original position
 			progress := float64(globalCharPos) / float64(totalChars-1)
-			color := m.interpolateColor("#FF7CCB", "#FDFF8C", progress)
+			color := m.interpolateColor(m.gradientStart, m.gradientEnd, progress)
 			
 			if float64(totalSecondsElapsed) > timeThreshold {
 				// This character is dimmed (elapsed) - use dimmed version of the gradient color
@@ -219,45 +487,60 @@ func (m *TimerModel) parseHexColor(hex string) [3]int {
 }
 
 func (m TimerModel) getCurrentSessionDuration() time.Duration {
-	switch m.sessionType {
-	case work:
-		if m.customDuration != nil {
-			return *m.customDuration
-		}
-		return 25 * time.Minute
-	case shortBreak:
-		if m.customShortBreak != nil {
-			return *m.customShortBreak
-		}
-		return 5 * time.Minute
-	case longBreak:
-		if m.customLongBreak != nil {
-			return *m.customLongBreak
-		}
-		return 15 * time.Minute
-	default:
-		return 25 * time.Minute
+	if m.cycleIndex < len(m.cycle) {
+		return m.cycle[m.cycleIndex].duration
 	}
+	return 25 * time.Minute
 }
 
+// nextSession moves on to the next segment of the cycle.
 func (m TimerModel) nextSession() TimerModel {
-	switch m.sessionType {
-	case work:
+	if m.sessionType == work {
 		m.sessionCount++
-		if m.sessionCount%4 == 0 {
-			m.sessionType = longBreak
-		} else {
-			m.sessionType = shortBreak
-		}
-	case shortBreak, longBreak:
-		m.sessionType = work
 	}
+	m.cycleIndex = (m.cycleIndex + 1) % len(m.cycle)
+	m.sessionType = m.cycle[m.cycleIndex].kind
 
 	duration := m.getCurrentSessionDuration()
 	m.timer = timer.NewWithInterval(duration, time.Second)
+	m.sessionStart = time.Time{}
+	m.elapsed = 0
+	m.pauses = 0
+	m.pendingConfirm = false
 	return m
 }
 
+// recordSession appends the current session, ended at the given time, to the
+// history log and returns a command reporting it, and the failure to write
+// it if any. Sessions that were never started are not recorded.
+func (m TimerModel) recordSession(completed bool, endedAt time.Time) tea.Cmd {
+	if m.sessionStart.IsZero() || m.readOnly {
+		return nil
+	}
+
+	dir, _ := os.Getwd()
+	record := SessionRecord{
+		Type:           m.sessionType.String(),
+		PlannedSeconds: int(m.getCurrentSessionDuration().Seconds()),
+		ElapsedSeconds: int(m.elapsed.Round(time.Second).Seconds()),
+		Pauses:         m.pauses,
+		StartedAt:      m.sessionStart,
+		EndedAt:        endedAt,
+		Dir:            dir,
+		Completed:      completed,
+	}
+	recorded := func() tea.Msg {
+		return sessionRecordedMsg{record}
+	}
+	if err := appendSessionRecord(record); err != nil {
+		failed := func() tea.Msg {
+			return timerWarningMsg{fmt.Errorf("could not record session in history: %v", err)}
+		}
+		return tea.Batch(recorded, failed)
+	}
+	return recorded
+}
+
 func (m TimerModel) getSessionName() string {
 	switch m.sessionType {
 	case work:
@@ -319,21 +602,59 @@ func (m *TimerModel) ViewWithTodos(todos []TodoItem) string {
 	var status string
 	if m.IsRunning() {
 		status = "Running ⏱️"
+	} else if m.sessionStart.IsZero() {
+		status = "Waiting to start ▶️"
 	} else {
 		status = "Paused ⏸️"
 	}
 
 	statusInfo := statusStyle.Render(fmt.Sprintf("%s | Sessions: %d", status, m.sessionCount))
+	if m.remote {
+		statusInfo = statusStyle.Render(fmt.Sprintf("%s | Sessions: %d | daemon", status, m.sessionCount))
+		if m.remoteErr != nil {
+			statusInfo = statusStyle.Render(fmt.Sprintf("Daemon unreachable: %v", m.remoteErr))
+		}
+	}
+	if m.pendingResume != nil {
+		state := m.pendingResume
+		prompt := fmt.Sprintf("Resume %s session with %s left? (y/n)", state.sessionName(), state.Remaining.Round(time.Second))
+		if state.Running {
+			prompt = fmt.Sprintf("Resume running %s session? (y/n)", state.sessionName())
+		}
+		statusInfo = statusStyle.Render(prompt)
+	} else if m.pendingConfirm {
+		prompt := fmt.Sprintf("Start %s now? (y: start, n: skip)", m.getSessionName())
+		statusInfo = statusStyle.Render(prompt)
+	} else if m.pendingGap > 0 {
+		prompt := fmt.Sprintf("Away for %s. Count it towards this session? (y/n)", m.pendingGap.Round(time.Second))
+		statusInfo = statusStyle.Render(prompt)
+	}
+
+	if m.warning != nil {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			MarginBottom(1).
+			Align(lipgloss.Center).
+			Width(width)
+		statusInfo = lipgloss.JoinVertical(lipgloss.Left, statusInfo, errorStyle.Render("⚠️ "+m.warning.Error()))
+	}
 
 	// Create todo summary
 	var todoSummary string
 	if len(todos) == 0 {
 		todoSummary = "No todos yet (Press Tab to add some)"
 	} else {
+		now := time.Now()
+		overdueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
 		var recentTodos []string
 		for i, todo := range todos {
 			if i < 3 { // Show first 3 todos
-				recentTodos = append(recentTodos, fmt.Sprintf("• %s", todo.Text))
+				line := fmt.Sprintf("%s• %s", strings.Repeat("  ", todo.depth), todo.Title())
+				if todo.overdue(now) {
+					line = overdueStyle.Render(fmt.Sprintf("%s (%s)", line, todo.dueLabel(now)))
+				}
+				recentTodos = append(recentTodos, line)
 			}
 		}
 
@@ -349,12 +670,26 @@ func (m *TimerModel) ViewWithTodos(todos []TodoItem) string {
 
 	todoSummaryDisplay := todoSummaryStyle.Render(todoSummary)
 
-	return lipgloss.JoinVertical(
-		lipgloss.Left,
-		timerDisplay,
-		statusInfo,
-		todoSummaryDisplay,
-	)
+	sections := []string{timerDisplay}
+
+	// Show the todo the sessions are for right below the timer
+	if i := focusIndex(todos); i >= 0 {
+		focusStyle := lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("212")).
+			MarginBottom(1).
+			Align(lipgloss.Center).
+			Width(width)
+
+		focus := "🎯 " + todos[i].Text
+		if summary := todos[i].focusSummary(); summary != "" {
+			focus += "  " + summary
+		}
+		sections = append(sections, focusStyle.Render(focus))
+	}
+
+	sections = append(sections, statusInfo, todoSummaryDisplay)
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
 func (m TimerModel) View() string {
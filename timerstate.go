@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// timerState is the part of TimerModel that is persisted per directory so a
+// session can be resumed after pom is closed or crashes.
+type timerState struct {
+	SessionType  string        `json:"session_type"`
+	SessionCount int           `json:"session_count"`
+	CycleIndex   int           `json:"cycle_index"`
+	Remaining    time.Duration `json:"remaining"`
+	Running      bool          `json:"running"`
+	SessionStart time.Time     `json:"session_start"`
+	Elapsed      time.Duration `json:"elapsed"`
+	Pauses       int           `json:"pauses"`
+	Confirm      bool          `json:"awaiting_confirmation"`
+	SavedAt      time.Time     `json:"saved_at"`
+}
+
+// worthResuming reports whether the state differs from a freshly started
+// timer, i.e. whether there is anything to offer to resume.
+func (s timerState) worthResuming() bool {
+	return s.SessionCount > 0 || s.Running || !s.SessionStart.IsZero() || s.Confirm
+}
+
+func (s timerState) sessionName() string {
+	return TimerModel{sessionType: parseSessionType(s.SessionType)}.getSessionName()
+}
+
+func parseSessionType(name string) sessionType {
+	switch name {
+	case shortBreak.String():
+		return shortBreak
+	case longBreak.String():
+		return longBreak
+	default:
+		return work
+	}
+}
+
+func saveTimerState(state timerState) error {
+	filename, err := getTimerStateFilename()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// loadTimerState returns the saved timer state for the current directory,
+// or nil if there is none.
+func loadTimerState() (*timerState, error) {
+	filename, err := getTimerStateFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state timerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (m TimerModel) snapshot() timerState {
+	now := wallClock()
+	remaining := m.timer.Timeout
+	if m.isRunning {
+		remaining = m.deadline.Sub(now)
+	}
+
+	return timerState{
+		SessionType:  m.sessionType.String(),
+		SessionCount: m.sessionCount,
+		CycleIndex:   m.cycleIndex,
+		Remaining:    remaining,
+		Running:      m.IsRunning(),
+		SessionStart: m.sessionStart,
+		Elapsed:      m.elapsed,
+		Pauses:       m.pauses,
+		Confirm:      m.pendingConfirm,
+		SavedAt:      now,
+	}
+}
+
+func (m TimerModel) saveState() {
+	if m.readOnly {
+		return
+	}
+	saveTimerState(m.snapshot())
+}
+
+// offerResume loads the saved state for the current directory and, if there
+// is one worth resuming, asks the user about it before the timer is used.
+func (m *TimerModel) offerResume() {
+	state, err := loadTimerState()
+	if err != nil || state == nil || !state.worthResuming() {
+		return
+	}
+	m.pendingResume = state
+}
+
+// findCycleIndex returns the saved cycle position if the current cycle has a
+// segment of the saved type there. When the cycle changed since the state
+// was saved, the first segment of that type is used instead.
+func (m TimerModel) findCycleIndex(index int, kind sessionType) int {
+	if index >= 0 && index < len(m.cycle) && m.cycle[index].kind == kind {
+		return index
+	}
+	for i, seg := range m.cycle {
+		if seg.kind == kind {
+			return i
+		}
+	}
+	return 0
+}
+
+// restore applies a saved state. Time that passed on the wall clock while a
+// running session was closed counts towards it. A session that would have
+// run out in the meantime is completed and followed by the next session,
+// which keeps running if it would still be. pom does not make up sessions
+// beyond that: after a longer absence the next session waits to be started.
+// The returned command reports the session completed that way.
+func (m TimerModel) restore(state timerState) (TimerModel, tea.Cmd) {
+	m.cycleIndex = m.findCycleIndex(state.CycleIndex, parseSessionType(state.SessionType))
+	m.sessionType = m.cycle[m.cycleIndex].kind
+	m.sessionCount = state.SessionCount
+	m.isRunning = state.Running
+	m.sessionStart = state.SessionStart
+	m.elapsed = state.Elapsed
+	m.pauses = state.Pauses
+	m.pendingConfirm = state.Confirm
+
+	duration := m.getCurrentSessionDuration()
+	remaining := state.Remaining
+	if remaining > duration || state.SessionStart.IsZero() {
+		remaining = duration
+	}
+
+	var recorded tea.Cmd
+	if state.Running {
+		at := state.SavedAt
+		now := wallClock()
+		if at.Add(remaining).Before(now) {
+			at = at.Add(remaining)
+			m.elapsed += remaining
+			recorded = m.recordSession(true, at)
+			m = m.nextSession()
+			remaining = m.getCurrentSessionDuration()
+			policy := m.startPolicyFor(m.sessionType)
+			if policy != startAuto || at.Add(remaining).Before(now) {
+				m.isRunning = false
+				m.pendingConfirm = policy == startConfirm
+				m.timer.Timeout = remaining
+				return m, recorded
+			}
+			m.sessionStart = at
+		}
+		passed := now.Sub(at)
+		remaining -= passed
+		m.elapsed += passed
+		m.deadline = now.Add(remaining)
+		m.lastTick = now
+	}
+
+	m.timer.Timeout = remaining
+	return m, recorded
+}
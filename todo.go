@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
@@ -11,15 +13,50 @@ import (
 )
 
 type TodoItem struct {
-	Text      string `json:"text"`
-	Completed bool   `json:"completed"`
-	ID        int    `json:"id"`
+	Text         string     `json:"text"`
+	Completed    bool       `json:"completed"`
+	ID           int        `json:"id"`
+	Focus        bool       `json:"focus,omitempty"`
+	Pomodoros    int        `json:"pomodoros,omitempty"`
+	FocusSeconds int        `json:"focus_seconds,omitempty"`
+	Estimate     int        `json:"estimate,omitempty"`
+	Priority     priority   `json:"priority,omitempty"`
+	Due          string     `json:"due,omitempty"`
+	CreatedAt    time.Time  `json:"created_at,omitzero"`
+	Projects     []string   `json:"projects,omitempty"`
+	Contexts     []string   `json:"contexts,omitempty"`
+	ParentID     int        `json:"parent_id,omitempty"`
+	Collapsed    bool       `json:"collapsed,omitempty"`
+	Order        int        `json:"order,omitempty"`
+	CompletedAt  time.Time  `json:"completed_at,omitzero"`
+	Recur        string     `json:"recur,omitempty"`
+	Scheduled    string     `json:"scheduled,omitempty"`
+	Source       todoSource `json:"source,omitzero"`
+
+	// Set by arrangeTodos for display
+	depth        int
+	subtasks     int
+	subtasksDone int
 }
 
 func (t TodoItem) FilterValue() string { return t.Text }
-func (t TodoItem) Title() string       { return t.Text }
+func (t TodoItem) Title() string {
+	if t.Focus {
+		return "🎯 " + t.Text
+	}
+	return t.Text
+}
 func (t TodoItem) Description() string {
-	return ""
+	return t.focusSummary()
+}
+
+// focusSummary describes the pomodoros and time spent on the todo, e.g.
+// "🍅 3 · 1h 15m", or is empty if no work session was spent on it yet.
+func (t TodoItem) focusSummary() string {
+	if t.Pomodoros == 0 && t.FocusSeconds == 0 {
+		return ""
+	}
+	return fmt.Sprintf("🍅 %d · %s", t.Pomodoros, formatDuration(time.Duration(t.FocusSeconds)*time.Second))
 }
 
 type todoMode int
@@ -28,6 +65,8 @@ const (
 	browsing todoMode = iota
 	adding
 	editing
+	archiving
+	pickingComments
 )
 
 type TodoModel struct {
@@ -38,6 +77,27 @@ type TodoModel struct {
 	todos      []TodoItem
 	nextID     int
 	editingIdx int
+	sort       todoSort
+	webhooks   Webhooks
+	undo       []todoSnapshot
+	redo       []todoSnapshot
+	status     string
+	statusID   int
+
+	store        todoStore
+	storeModTime time.Time
+	// stored are the todos as they were last read from or written to the
+	// store, to tell the changes made here from those made elsewhere
+	stored []TodoItem
+	// storeStatus clears the status set while saving, which is shown
+	// until the store is checked next
+	storeStatus tea.Cmd
+
+	archive       []TodoItem
+	archiveCursor int
+	autoArchive   bool
+
+	picker commentPicker
 }
 
 type TodoKeyMap struct {
@@ -76,10 +136,10 @@ func DefaultTodoKeys() TodoKeyMap {
 func NewTodoModel() TodoModel {
 	items := []list.Item{}
 
-	l := list.New(items, list.NewDefaultDelegate(), 50, 10)
+	l := list.New(items, todoDelegate{}, 50, 10)
 	l.Title = "📝 Todo List"
 	l.SetShowStatusBar(false)
-	l.SetFilteringEnabled(false)
+	l.Filter = filterTodos
 	l.SetShowHelp(false)
 
 	ta := textarea.New()
@@ -96,24 +156,79 @@ func NewTodoModel() TodoModel {
 		keys:     DefaultTodoKeys(),
 		todos:    []TodoItem{},
 		nextID:   1,
+		store:    openTodoStore(),
 	}
 
 	tm.loadTodos()
 	return tm
 }
 
+// todoDayMsg is sent at midnight, when recurring todos may be due again
+// and completed todos may be archived.
+type todoDayMsg struct{}
+
+// waitForNextDay returns a command sending todoDayMsg just after the next
+// midnight.
+func waitForNextDay() tea.Cmd {
+	midnight := startOfDay(time.Now()).AddDate(0, 0, 1)
+	return tea.Tick(time.Until(midnight)+time.Second, func(time.Time) tea.Msg {
+		return todoDayMsg{}
+	})
+}
+
 func (m TodoModel) Init() tea.Cmd {
-	return nil
+	return tea.Batch(waitForNextDay(), checkTodoStore())
 }
 
 func (m TodoModel) Update(msg tea.Msg) (TodoModel, tea.Cmd) {
 	switch msg := msg.(type) {
+	case todoStatusClearMsg:
+		if msg.id == m.statusID {
+			m.status = ""
+		}
+		return m, nil
+
+	case commentsScannedMsg:
+		if msg.err != nil {
+			return m, m.setStatus(fmt.Sprintf("Could not scan for comments: %v", msg.err))
+		}
+		return m, m.pickComments(msg.comments)
+
+	case todoStoreCheckMsg:
+		cleared := m.storeStatus
+		m.storeStatus = nil
+		return m, tea.Batch(cleared, m.reloadIfChanged(), checkTodoStore())
+
+	case todoDayMsg:
+		if m.materializeRecurrences(time.Now()) {
+			m.updateList()
+			m.saveTodos()
+		}
+		if m.autoArchive {
+			m.archiveCompleted(startOfDay(time.Now()))
+		}
+		return m, waitForNextDay()
+
 	case tea.WindowSizeMsg:
 		m.list.SetWidth(msg.Width)
 		m.list.SetHeight(msg.Height - 10)
 		return m, nil
 
 	case tea.KeyMsg:
+		if m.list.SettingFilter() {
+			// Typing a filter
+			var cmd tea.Cmd
+			m.list, cmd = m.list.Update(msg)
+			return m, cmd
+		}
+
+		if m.mode == archiving {
+			return m.updateArchive(msg)
+		}
+		if m.mode == pickingComments {
+			return m.updatePicker(msg)
+		}
+
 		if m.mode == adding {
 			switch msg.String() {
 			case "enter":
@@ -161,7 +276,7 @@ func (m TodoModel) Update(msg tea.Msg) (TodoModel, tea.Cmd) {
 				return m, m.textarea.Focus()
 			case "d":
 				if len(m.todos) > 0 {
-					selected := m.list.Index()
+					selected := m.selectedIndex()
 					if selected >= 0 && selected < len(m.todos) {
 						m.deleteTodo(selected)
 					}
@@ -169,7 +284,7 @@ func (m TodoModel) Update(msg tea.Msg) (TodoModel, tea.Cmd) {
 				return m, nil
 			case "e":
 				if len(m.todos) > 0 {
-					selected := m.list.Index()
+					selected := m.selectedIndex()
 					if selected >= 0 && selected < len(m.todos) {
 						m.editTodo(selected)
 						m.mode = editing
@@ -178,12 +293,57 @@ func (m TodoModel) Update(msg tea.Msg) (TodoModel, tea.Cmd) {
 				return m, m.textarea.Focus()
 			case "enter":
 				if len(m.todos) > 0 {
-					selected := m.list.Index()
+					selected := m.selectedIndex()
 					if selected >= 0 && selected < len(m.todos) {
-						m.toggleTodo(selected)
+						return m, m.toggleTodo(selected)
 					}
 				}
 				return m, nil
+			case "f":
+				if len(m.todos) > 0 {
+					selected := m.selectedIndex()
+					if selected >= 0 && selected < len(m.todos) {
+						m.focusTodo(selected)
+					}
+				}
+				return m, nil
+			case "s":
+				m.setSort((m.sort + 1) % numSorts)
+				return m, nil
+			case "t":
+				m.nextTagFilter()
+				return m, nil
+			case ">":
+				m.indentTodo(m.selectedIndex())
+				return m, nil
+			case "<":
+				m.outdentTodo(m.selectedIndex())
+				return m, nil
+			case "c":
+				m.toggleCollapsed(m.selectedIndex())
+				return m, nil
+			case "A":
+				n := m.archiveCompleted(time.Time{})
+				if n == 0 {
+					return m, m.setStatus("No completed todos to archive")
+				}
+				return m, m.setStatus("Archived " + todoCount(n))
+			case "v":
+				m.mode = archiving
+				m.archiveCursor = 0
+				return m, nil
+			case "i":
+				return m, tea.Batch(m.setStatus("Scanning for comments..."), scanCommentsCmd())
+			case "u":
+				return m, m.undoChange()
+			case "ctrl+r":
+				return m, m.redoChange()
+			case "K", "shift+up":
+				m.moveTodo(m.selectedIndex(), -1)
+				return m, nil
+			case "J", "shift+down":
+				m.moveTodo(m.selectedIndex(), 1)
+				return m, nil
 			}
 		}
 	}
@@ -198,76 +358,330 @@ func (m TodoModel) Update(msg tea.Msg) (TodoModel, tea.Cmd) {
 	return m, cmd
 }
 
+// addTodo adds a todo typed by the user. Input without any text besides
+// inline tokens is ignored.
 func (m *TodoModel) addTodo(text string) {
+	input := parseTodoInput(text)
+	if input.Text == "" {
+		return
+	}
+
 	todo := TodoItem{
-		Text:      text,
 		Completed: false,
 		ID:        m.nextID,
+		CreatedAt: time.Now(),
 	}
+	input.apply(&todo)
+	m.remember("add", todo)
 	m.nextID++
 	m.todos = append(m.todos, todo)
 	m.updateList()
 	m.saveTodos()
 }
 
+// deleteTodo deletes a todo together with its subtasks.
 func (m *TodoModel) deleteTodo(index int) {
 	if index >= 0 && index < len(m.todos) {
-		m.todos = append(m.todos[:index], m.todos[index+1:]...)
+		m.remember("delete", m.todos[index])
+		deleted := subtreeIDs(m.todos, m.todos[index].ID)
+		var kept []TodoItem
+		for _, todo := range m.todos {
+			if !deleted[todo.ID] {
+				kept = append(kept, todo)
+			}
+		}
+		m.todos = kept
 		m.updateList()
 		m.saveTodos()
 	}
 }
 
-func (m *TodoModel) toggleTodo(index int) {
-	if index >= 0 && index < len(m.todos) {
-		m.todos[index].Completed = !m.todos[index].Completed
-		m.updateList()
-		m.saveTodos()
+// toggleTodo flips the completion of a todo, completing or reopening its
+// parents to match. The returned command delivers the webhooks for
+// completed todos.
+func (m *TodoModel) toggleTodo(index int) tea.Cmd {
+	if index < 0 || index >= len(m.todos) {
+		return nil
+	}
+
+	change := "complete"
+	if m.todos[index].Completed {
+		change = "reopen"
+	}
+	m.remember(change, m.todos[index])
+
+	m.todos[index].Completed = !m.todos[index].Completed
+	changed := append([]int{index}, m.updateParents(m.todos[index].ID)...)
+
+	var events []webhookPayload
+	var recurring, reopened []TodoItem
+	for _, i := range changed {
+		if m.todos[i].Completed {
+			m.todos[i].Focus = false
+			m.todos[i].CompletedAt = time.Now()
+			events = append(events, todoCompletedEvent(m.todos[i]))
+			if m.todos[i].Recur != "" {
+				recurring = append(recurring, m.todos[i])
+			}
+		} else {
+			m.todos[i].CompletedAt = time.Time{}
+			if m.todos[i].Recur != "" {
+				reopened = append(reopened, m.todos[i])
+			}
+		}
+	}
+
+	// A reopened recurring todo takes back the occurrence its completion
+	// added, unless that already came up or was worked on
+	for _, todo := range reopened {
+		if at := m.pendingRecurrence(todo); at >= 0 && m.untouchedRecurrence(at, time.Now()) {
+			m.todos = append(m.todos[:at], m.todos[at+1:]...)
+		}
+	}
+
+	// Completed recurring todos come back on their next day, right after
+	// the completed one. One that is still open already has its next
+	// occurrence.
+	for _, todo := range recurring {
+		if m.pendingRecurrence(todo) >= 0 {
+			continue
+		}
+		next := todo.nextRecurrence(m.nextID, time.Now())
+		m.nextID++
+		at := m.findTodo(todo.ID) + 1
+		m.todos = append(m.todos[:at], append([]TodoItem{next}, m.todos[at:]...)...)
+	}
+	m.updateList()
+	m.saveTodos()
+
+	if len(events) == 0 {
+		return nil
+	}
+	return m.webhooks.send(events...)
+}
+
+// moveTodo moves a todo, with its subtasks, up (delta -1) or down (delta
+// 1) past its neighbouring sibling. Todos can only be moved while the list
+// is in manual order.
+func (m *TodoModel) moveTodo(index, delta int) {
+	if index < 0 || index >= len(m.todos) || m.sort != sortManual {
+		return
+	}
+
+	var siblings []int
+	position := -1
+	for _, todo := range m.visibleTodos() {
+		if todo.ParentID != m.todos[index].ParentID {
+			continue
+		}
+		if todo.ID == m.todos[index].ID {
+			position = len(siblings)
+		}
+		siblings = append(siblings, m.findTodo(todo.ID))
+	}
+
+	target := position + delta
+	if position < 0 || target < 0 || target >= len(siblings) {
+		return
+	}
+	other := siblings[target]
+	m.remember("move", m.todos[index])
+	m.todos[index], m.todos[other] = m.todos[other], m.todos[index]
+	m.updateList()
+	m.saveTodos()
+}
+
+// focusTodo makes a todo the one work sessions are credited to, or clears
+// the focus if it already is. Completed todos cannot be focused.
+func (m *TodoModel) focusTodo(index int) {
+	if index < 0 || index >= len(m.todos) || m.todos[index].Completed {
+		return
+	}
+
+	m.remember("focus", m.todos[index])
+	focus := !m.todos[index].Focus
+	for i := range m.todos {
+		m.todos[i].Focus = false
+	}
+	m.todos[index].Focus = focus
+	m.updateList()
+	m.saveTodos()
+}
+
+// creditFocus adds a finished work session to the focus todo. Only
+// sessions that ran out count as pomodoros, but all focused time counts.
+func (m *TodoModel) creditFocus(record SessionRecord) {
+	index := focusIndex(m.todos)
+	if record.Type != work.String() || index < 0 {
+		return
+	}
+
+	m.todos[index].FocusSeconds += record.ElapsedSeconds
+	if record.Completed {
+		m.todos[index].Pomodoros++
+	}
+	m.updateList()
+	m.saveTodos()
+}
+
+// focusIndex returns the index of the focus todo, or -1 if there is none.
+func focusIndex(todos []TodoItem) int {
+	for i, todo := range todos {
+		if todo.Focus {
+			return i
+		}
 	}
+	return -1
 }
 
 func (m *TodoModel) editTodo(index int) {
 	if index >= 0 && index < len(m.todos) {
 		m.editingIdx = index
-		m.textarea.SetValue(m.todos[index].Text)
+		m.textarea.SetValue(m.todos[index].inputText())
 	}
 }
 
 func (m *TodoModel) updateTodo(index int, text string) {
-	if index >= 0 && index < len(m.todos) {
-		m.todos[index].Text = text
+	input := parseTodoInput(text)
+	if index >= 0 && index < len(m.todos) && input.Text != "" {
+		m.remember("edit", m.todos[index])
+		input.apply(&m.todos[index])
 		m.updateList()
 		m.saveTodos()
 	}
 }
 
-func (m *TodoModel) updateList() {
-	items := make([]list.Item, len(m.todos))
+// findTodo returns the index of the todo with the given ID, or -1.
+func (m TodoModel) findTodo(id int) int {
 	for i, todo := range m.todos {
+		if todo.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectedIndex returns the index in m.todos of the selected todo, or -1.
+func (m TodoModel) selectedIndex() int {
+	todo, ok := m.list.SelectedItem().(TodoItem)
+	if !ok {
+		return -1
+	}
+	return m.findTodo(todo.ID)
+}
+
+// visibleTodos returns the todos in the order they are shown, with the
+// subtasks of collapsed todos and todos scheduled for a later day left
+// out.
+func (m TodoModel) visibleTodos() []TodoItem {
+	now := time.Now()
+	var todos []TodoItem
+	for _, todo := range m.todos {
+		if !todo.scheduled(now) {
+			todos = append(todos, todo)
+		}
+	}
+	return arrangeTodos(todos, m.sort, false)
+}
+
+// capturingInput reports whether keys are being typed into the todo list,
+// so they must not be taken as shortcuts.
+func (m TodoModel) capturingInput() bool {
+	return m.mode == adding || m.mode == editing || m.list.SettingFilter()
+}
+
+// setSort changes the order of the list.
+func (m *TodoModel) setSort(mode todoSort) {
+	m.sort = mode
+	m.updateList()
+}
+
+// updateList shows the current todos in the list, keeping the selected
+// todo selected.
+func (m *TodoModel) updateList() {
+	selected, hasSelection := m.list.SelectedItem().(TodoItem)
+
+	visible := m.visibleTodos()
+	items := make([]list.Item, len(visible))
+	for i, todo := range visible {
 		items[i] = todo
 	}
 	m.list.SetItems(items)
+
+	// Filter the new items right away rather than in a command
+	if m.list.FilterState() == list.FilterApplied {
+		m.list.SetFilterText(m.list.FilterValue())
+	}
+
+	if !hasSelection {
+		return
+	}
+	for i, item := range m.list.VisibleItems() {
+		if item.(TodoItem).ID == selected.ID {
+			m.list.Select(i)
+		}
+	}
 }
 
+// saveTodos saves the todos, numbering them in their manual order. When
+// another program changed the store since it was read, its changes are
+// merged with the ones made here rather than overwritten.
 func (m *TodoModel) saveTodos() {
-	saveTodosToFile(m.todos)
+	if !m.store.modTime().Equal(m.storeModTime) {
+		theirs, err := m.readStore()
+		if err != nil {
+			m.storeStatus = m.setStatus(fmt.Sprintf("Not saved: could not read %s: %v", m.store.name(), err))
+			return
+		}
+		m.nextID = assignTodoIDs(theirs, m.stored, max(m.nextID, maxTodoID(theirs)+1))
+		m.todos = mergeTodos(m.stored, m.todos, theirs)
+		m.updateList()
+		m.storeStatus = m.setStatus("Merged changes made to " + m.store.name() + " elsewhere")
+	}
+
+	for i := range m.todos {
+		m.todos[i].Order = i + 1
+	}
+	m.store.save(m.todos)
+	m.storeModTime = m.store.modTime()
+	m.stored = append([]TodoItem(nil), m.todos...)
+}
+
+// readStore loads the todos from the store in their manual order.
+func (m *TodoModel) readStore() ([]TodoItem, error) {
+	todos, err := m.store.load()
+	if err != nil {
+		return nil, err
+	}
+	m.storeModTime = m.store.modTime()
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		return todos[i].Order < todos[j].Order
+	})
+	return todos, nil
 }
 
 func (m *TodoModel) loadTodos() {
-	todos, err := loadTodosFromFile()
+	todos, err := m.readStore()
 	if err != nil {
 		return
 	}
-
 	m.todos = todos
+	if m.materializeRecurrences(time.Now()) {
+		m.saveTodos()
+	}
 
-	maxID := 0
-	for _, todo := range m.todos {
-		if todo.ID > maxID {
-			maxID = todo.ID
-		}
+	if archive, err := loadArchiveFromFile(); err == nil {
+		m.archive = archive
 	}
-	m.nextID = maxID + 1
+
+	// IDs of archived todos stay taken so they can be restored
+	m.nextID = max(maxTodoID(m.todos), maxTodoID(m.archive)) + 1
+
+	// Todos added to the store by other programs have no ID yet. Their
+	// IDs are only written with the next change made here.
+	m.nextID = assignTodoIDs(m.todos, m.stored, m.nextID)
+	m.stored = append([]TodoItem(nil), m.todos...)
 
 	m.updateList()
 }
@@ -275,6 +689,26 @@ func (m *TodoModel) loadTodos() {
 func (m TodoModel) View() string {
 	width := 60 // Fixed width for consistent centering
 
+	if m.mode == archiving {
+		help := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			MarginTop(1).
+			Render("↑/↓: move • r: restore • esc: back")
+		sections := []string{m.archiveView(width)}
+		if m.status != "" {
+			sections = append(sections, lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(m.status))
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, append(sections, help)...)
+	}
+
+	if m.mode == pickingComments {
+		help := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			MarginTop(1).
+			Render("↑/↓: move • space: choose • a: all • enter: import chosen • esc: cancel")
+		return lipgloss.JoinVertical(lipgloss.Left, m.pickerView(width), help)
+	}
+
 	if m.mode == adding {
 		addStyle := lipgloss.NewStyle().
 			Align(lipgloss.Center).
@@ -305,7 +739,7 @@ func (m TodoModel) View() string {
 		Align(lipgloss.Center).
 		Width(width)
 
-	help := helpStyle.Render("a: add • e: edit • enter: toggle • d: delete")
+	help := helpStyle.Render(fmt.Sprintf("a: add • e: edit • enter: toggle • f: focus • d: delete • >/<: indent • c: collapse • K/J: move • u/ctrl+r: undo/redo • A: archive done • v: view archive • i: scan comments • s: sort (%s) • /: filter • t: tags", m.sort))
 
 	// Debug: show todos directly if list is empty
 	debugStyle := lipgloss.NewStyle().
@@ -319,12 +753,13 @@ func (m TodoModel) View() string {
 		debugInfo = debugStyle.Render("No todos yet. Press 'a' to add one.")
 	} else {
 		debugContent := ""
-		for i, todo := range m.todos {
+		for i, item := range m.list.VisibleItems() {
+			todo := item.(TodoItem)
 			marker := "  "
 			if i == m.list.Index() {
 				marker = "▶ "
 			}
-			debugContent += fmt.Sprintf("\n%s• %s", marker, todo.Text)
+			debugContent += fmt.Sprintf("\n%s• %s", marker, todo.Title())
 		}
 		debugInfo = debugStyle.Render(debugContent)
 	}
@@ -333,11 +768,19 @@ func (m TodoModel) View() string {
 		Align(lipgloss.Center).
 		Width(width)
 
-	return lipgloss.JoinVertical(
-		lipgloss.Center,
-		debugInfo,
-		"",
-		listStyle.Render(m.list.View()),
-		help,
-	)
+	sections := []string{debugInfo, ""}
+	if tagBar := m.tagBar(width); tagBar != "" {
+		sections = append(sections, tagBar, "")
+	}
+	sections = append(sections, listStyle.Render(m.list.View()))
+	if m.status != "" {
+		statusStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("205")).
+			Align(lipgloss.Center).
+			Width(width)
+		sections = append(sections, statusStyle.Render(m.status))
+	}
+	sections = append(sections, help)
+
+	return lipgloss.JoinVertical(lipgloss.Center, sections...)
 }
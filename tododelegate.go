@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// todoDelegate renders a todo on a single line together with its progress
+// against the estimate.
+type todoDelegate struct{}
+
+func (d todoDelegate) Height() int                             { return 1 }
+func (d todoDelegate) Spacing() int                            { return 0 }
+func (d todoDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+
+func (d todoDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	todo, ok := item.(TodoItem)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	style := lipgloss.NewStyle()
+	detailStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	if todo.Completed {
+		style = style.Foreground(lipgloss.Color("241")).Strikethrough(true)
+	} else if todo.overdue(now) {
+		style = style.Foreground(lipgloss.Color("196"))
+		detailStyle = detailStyle.Foreground(lipgloss.Color("196"))
+	}
+
+	cursor := "  "
+	if index == m.Index() {
+		cursor = "▶ "
+		if !todo.Completed {
+			style = style.Foreground(lipgloss.Color("205")).Bold(true)
+		}
+	}
+
+	check := "[ ]"
+	if todo.Completed {
+		check = "[x]"
+	}
+
+	fold := "  "
+	if todo.subtasks > 0 && todo.Collapsed {
+		fold = "▸ "
+	} else if todo.subtasks > 0 {
+		fold = "▾ "
+	}
+
+	indent := strings.Repeat("  ", todo.depth)
+	line := style.Render(fmt.Sprintf("%s%s%s%s %s", cursor, indent, fold, check, todo.Title()))
+	if todo.subtasks > 0 {
+		line += " " + detailStyle.Render(fmt.Sprintf("(%d/%d)", todo.subtasksDone, todo.subtasks))
+	}
+	if marker := todo.Priority.marker(); marker != "" && !todo.Completed {
+		line += " " + todo.Priority.style().Render(marker)
+	}
+	if due := todo.dueLabel(now); due != "" {
+		line += " " + detailStyle.Render(due)
+	}
+	if todo.Recur != "" {
+		line += " " + detailStyle.Render("↻ "+todo.Recur)
+	}
+	if progress := todo.progress(); progress != "" {
+		line += " " + detailStyle.Render(progress)
+	}
+	if todo.Source.Gone {
+		line += " " + detailStyle.Strikethrough(true).Render(todo.Source.String())
+	} else if todo.Source.File != "" {
+		line += " " + detailStyle.Render(todo.Source.String())
+	}
+	fmt.Fprint(w, lipgloss.NewStyle().MaxWidth(m.Width()).Render(line))
+}
+
+// marker is how the priority is shown next to a todo.
+func (p priority) marker() string {
+	return strings.Repeat("!", p.rank())
+}
+
+func (p priority) style() lipgloss.Style {
+	switch p {
+	case priorityHigh:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("203")).Bold(true)
+	case priorityMedium:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	default:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	}
+}
+
+// progress describes the pomodoros spent on the todo, against its estimate
+// if it has one.
+func (t TodoItem) progress() string {
+	if t.Estimate == 0 {
+		return t.focusSummary()
+	}
+
+	progress := t.estimateBar()
+	if t.FocusSeconds > 0 {
+		progress += " " + formatDuration(time.Duration(t.FocusSeconds)*time.Second)
+	}
+	return progress
+}
+
+// estimateBar shows the pomodoros spent against the estimate: "🍅🍅○○" for
+// two of four, "🍅🍅🍅+1" once an estimate of three is exceeded, and
+// "🍅 3/12" for estimates too large to draw.
+func (t TodoItem) estimateBar() string {
+	if t.Estimate > 8 {
+		return fmt.Sprintf("🍅 %d/%d", t.Pomodoros, t.Estimate)
+	}
+	if t.Pomodoros > t.Estimate {
+		return strings.Repeat("🍅", t.Estimate) + fmt.Sprintf("+%d", t.Pomodoros-t.Estimate)
+	}
+	return strings.Repeat("🍅", t.Pomodoros) + strings.Repeat("○", t.Estimate-t.Pomodoros)
+}
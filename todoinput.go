@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// todoInput is a todo as typed by the user: the text and the attributes
+// given with inline tokens, e.g. "Write report ~3 !high due:fri rec:weekly"
+// for an estimate of three pomodoros, high priority, due on Friday and
+// recurring every week. +project and @context tags are collected but stay
+// part of the text.
+type todoInput struct {
+	Text      string
+	Estimate  int
+	Priority  priority
+	Due       string
+	Projects  []string
+	Contexts  []string
+	Recur     string
+	Scheduled string
+}
+
+const maxEstimate = 99
+
+// dueLayout is the format of due dates, which are stored without a time.
+const dueLayout = "2006-01-02"
+
+func parseTodoInput(input string) todoInput {
+	return parseTodoInputAt(input, time.Now())
+}
+
+// parseTodoInputAt parses input with relative due dates such as "due:fri"
+// taken relative to now.
+func parseTodoInputAt(input string, now time.Time) todoInput {
+	var in todoInput
+	var words []string
+	for _, word := range strings.Fields(input) {
+		if n, ok := parseEstimate(word); ok {
+			in.Estimate = n
+			continue
+		}
+		if p, ok := parsePriority(word); ok {
+			in.Priority = p
+			continue
+		}
+		if due, ok := parseDateToken(word, "due:", now); ok {
+			in.Due = due.Format(dueLayout)
+			continue
+		}
+		if date, ok := parseDateToken(word, "t:", now); ok {
+			in.Scheduled = date.Format(dueLayout)
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(word), "rec:") {
+			if rule, ok := parseRecurrence(word[len("rec:"):]); ok {
+				in.Recur = rule
+				continue
+			}
+		}
+		if isTag(word) && word[0] == '+' {
+			in.Projects = append(in.Projects, word)
+		} else if isTag(word) {
+			in.Contexts = append(in.Contexts, word)
+		}
+		words = append(words, word)
+	}
+	in.Text = strings.Join(words, " ")
+	return in
+}
+
+// parseEstimate parses an estimate token such as "~3".
+func parseEstimate(word string) (int, bool) {
+	if !strings.HasPrefix(word, "~") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(word[1:])
+	if err != nil || n < 1 || n > maxEstimate {
+		return 0, false
+	}
+	return n, true
+}
+
+// priority is the priority of a todo: "high", "medium", "low" or empty.
+type priority string
+
+const (
+	priorityNone   priority = ""
+	priorityLow    priority = "low"
+	priorityMedium priority = "medium"
+	priorityHigh   priority = "high"
+)
+
+func (p priority) rank() int {
+	switch p {
+	case priorityHigh:
+		return 3
+	case priorityMedium:
+		return 2
+	case priorityLow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// parsePriority parses a priority token: "!high", "!med" or "!low", or
+// their first letter.
+func parsePriority(word string) (priority, bool) {
+	switch strings.ToLower(word) {
+	case "!high", "!h":
+		return priorityHigh, true
+	case "!medium", "!med", "!m":
+		return priorityMedium, true
+	case "!low", "!l":
+		return priorityLow, true
+	}
+	return priorityNone, false
+}
+
+// parseDateToken parses a date token with the given prefix, such as a due
+// date: "due:today", "due:tomorrow", a weekday such as "due:fri" for the
+// next such day (today included), or "due:2006-01-02".
+func parseDateToken(word, prefix string, now time.Time) (time.Time, bool) {
+	if !strings.HasPrefix(strings.ToLower(word), prefix) {
+		return time.Time{}, false
+	}
+	value := strings.ToLower(word[len(prefix):])
+	today := startOfDay(now)
+
+	switch value {
+	case "today":
+		return today, true
+	case "tomorrow", "tmr":
+		return today.AddDate(0, 0, 1), true
+	}
+
+	if day, ok := parseWeekday(value); ok {
+		offset := (int(day) - int(today.Weekday()) + 7) % 7
+		return today.AddDate(0, 0, offset), true
+	}
+
+	date, err := time.ParseInLocation(dueLayout, value, now.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// apply sets the attributes of the input on a todo.
+func (in todoInput) apply(todo *TodoItem) {
+	todo.Text = in.Text
+	todo.Estimate = in.Estimate
+	todo.Priority = in.Priority
+	todo.Due = in.Due
+	todo.Projects = in.Projects
+	todo.Contexts = in.Contexts
+	todo.Recur = in.Recur
+	todo.Scheduled = in.Scheduled
+}
+
+// inputText returns the todo the way it would be typed, so that editing it
+// keeps its attributes.
+func (t TodoItem) inputText() string {
+	words := []string{t.Text}
+	if t.Estimate > 0 {
+		words = append(words, fmt.Sprintf("~%d", t.Estimate))
+	}
+	if t.Priority != priorityNone {
+		words = append(words, "!"+string(t.Priority))
+	}
+	if t.Due != "" {
+		words = append(words, "due:"+t.Due)
+	}
+	if t.Recur != "" {
+		words = append(words, "rec:"+t.Recur)
+	}
+	if t.Scheduled != "" {
+		words = append(words, "t:"+t.Scheduled)
+	}
+	return strings.Join(words, " ")
+}
+
+// dueDate returns the day the todo is due in the local time zone.
+func (t TodoItem) dueDate() (time.Time, bool) {
+	if t.Due == "" {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(dueLayout, t.Due, time.Local)
+	return date, err == nil
+}
+
+// overdue reports whether an open todo was due before today.
+func (t TodoItem) overdue(now time.Time) bool {
+	due, ok := t.dueDate()
+	return ok && !t.Completed && due.Before(startOfDay(now))
+}
+
+// dueLabel describes the due date relative to now, e.g. "due tomorrow",
+// "due Fri" or "overdue 3d".
+func (t TodoItem) dueLabel(now time.Time) string {
+	due, ok := t.dueDate()
+	if !ok {
+		return ""
+	}
+
+	days := int(math.Round(due.Sub(startOfDay(now)).Hours() / 24))
+	switch {
+	case days < 0 && !t.Completed:
+		return fmt.Sprintf("overdue %dd", -days)
+	case days == 0:
+		return "due today"
+	case days == 1:
+		return "due tomorrow"
+	case days > 1 && days < 7:
+		return "due " + due.Format("Mon")
+	default:
+		return "due " + due.Format("Jan 2")
+	}
+}
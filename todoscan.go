@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// todoSource is the TODO or FIXME comment a todo was imported from.
+type todoSource struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Text is the comment as it was found, to recognize it on later scans
+	// even if the todo was edited
+	Text string `json:"text,omitempty"`
+	// Gone is set when the comment was no longer found
+	Gone bool `json:"gone,omitempty"`
+}
+
+func (s todoSource) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// codeComment is a TODO or FIXME comment found in a source file.
+type codeComment struct {
+	File string
+	Line int
+	Text string
+}
+
+// maxScanFileSize is the size above which files are not scanned, as they
+// are rarely source code.
+const maxScanFileSize = 1 << 20
+
+// codeCommentPattern matches a TODO: or FIXME: after a comment marker, such
+// as "// TODO: retry" or "# FIXME(ann): quoting".
+var codeCommentPattern = regexp.MustCompile(`(?://|#|/\*|\*|--|;|<!--|%)\s*(?:TODO|FIXME)(?:\([^)]*\))?:\s*(.*\S)`)
+
+// sourceFiles lists the files to scan: the files git knows about, or that
+// it would add, in a repository, and all files outside of hidden
+// directories otherwise.
+func sourceFiles(dir string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = dir
+	if output, err := cmd.Output(); err == nil {
+		var files []string
+		for _, file := range strings.Split(string(output), "\x00") {
+			if file != "" {
+				files = append(files, filepath.FromSlash(file))
+			}
+		}
+		return files, nil
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err == nil {
+				files = append(files, rel)
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// scanComments finds the TODO and FIXME comments in the files below dir.
+// Binary and very large files are skipped.
+func scanComments(dir string) ([]codeComment, error) {
+	files, err := sourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []codeComment
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxScanFileSize {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			continue
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, maxScanFileSize)
+		for line := 1; scanner.Scan(); line++ {
+			match := codeCommentPattern.FindStringSubmatch(scanner.Text())
+			if match == nil {
+				continue
+			}
+			text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(match[1], "*/"), "-->"))
+			if text != "" {
+				comments = append(comments, codeComment{File: filepath.ToSlash(file), Line: line, Text: text})
+			}
+		}
+	}
+	return comments, nil
+}
+
+// commentsScannedMsg carries the result of scanning the working directory.
+type commentsScannedMsg struct {
+	comments []codeComment
+	err      error
+}
+
+func scanCommentsCmd() tea.Cmd {
+	return func() tea.Msg {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return commentsScannedMsg{err: err}
+		}
+		comments, err := scanComments(cwd)
+		return commentsScannedMsg{comments: comments, err: err}
+	}
+}
+
+// commentKey identifies a comment across scans, when lines may have moved.
+func commentKey(file, text string) string {
+	return file + "\x00" + text
+}
+
+// sourceKey is the commentKey of the comment a todo was imported from.
+func (t TodoItem) sourceKey() string {
+	text := t.Source.Text
+	if text == "" {
+		text = t.Text
+	}
+	return commentKey(t.Source.File, text)
+}
+
+// commentScan is how a scan compares with the todos imported before.
+type commentScan struct {
+	found []codeComment
+	// known are the comments already imported, with their todos
+	known map[int]codeComment
+	// gone are the todos whose comment was not found
+	gone []int
+}
+
+// compareComments matches scanned comments with the todos, and archived
+// todos, imported from them before.
+func (m TodoModel) compareComments(comments []codeComment) commentScan {
+	byKey := map[string][]codeComment{}
+	for _, comment := range comments {
+		key := commentKey(comment.File, comment.Text)
+		byKey[key] = append(byKey[key], comment)
+	}
+
+	scan := commentScan{known: map[int]codeComment{}}
+	for _, todo := range append(append([]TodoItem{}, m.todos...), m.archive...) {
+		if todo.Source.File == "" {
+			continue
+		}
+		key := todo.sourceKey()
+		matches := byKey[key]
+		if len(matches) == 0 {
+			scan.gone = append(scan.gone, todo.ID)
+			continue
+		}
+		scan.known[todo.ID] = matches[0]
+		byKey[key] = matches[1:]
+	}
+
+	for _, comment := range comments {
+		key := commentKey(comment.File, comment.Text)
+		if len(byKey[key]) > 0 && byKey[key][0] == comment {
+			scan.found = append(scan.found, comment)
+			byKey[key] = byKey[key][1:]
+		}
+	}
+	return scan
+}
+
+// importComments adds todos for the chosen comments among those not
+// imported yet, updates the lines of known ones and marks the todos whose
+// comment is gone. It returns the number of todos added and of open todos
+// whose comment is gone.
+func (m *TodoModel) importComments(comments, chosen []codeComment) (int, int) {
+	scan := m.compareComments(comments)
+	adding := map[codeComment]bool{}
+	for _, comment := range chosen {
+		adding[comment] = true
+	}
+	var added []codeComment
+	for _, comment := range scan.found {
+		if adding[comment] {
+			added = append(added, comment)
+		}
+	}
+
+	gone := map[int]bool{}
+	for _, id := range scan.gone {
+		gone[id] = true
+	}
+
+	sources := map[int]todoSource{}
+	for _, todo := range m.todos {
+		if todo.Source.File == "" {
+			continue
+		}
+		source := todo.Source
+		if comment, ok := scan.known[todo.ID]; ok {
+			source.Line = comment.Line
+		}
+		source.Gone = gone[todo.ID]
+		if source != todo.Source {
+			sources[todo.ID] = source
+		}
+	}
+
+	if len(sources) > 0 || len(added) > 0 {
+		m.rememberChange("scan for comments")
+		for i, todo := range m.todos {
+			if source, ok := sources[todo.ID]; ok {
+				m.todos[i].Source = source
+			}
+		}
+		for _, comment := range added {
+			m.todos = append(m.todos, comment.todo(m.nextID))
+			m.nextID++
+		}
+		m.updateList()
+		m.saveTodos()
+	}
+
+	goneTodos := 0
+	for _, todo := range m.todos {
+		if todo.Source.Gone && !todo.Completed {
+			goneTodos++
+		}
+	}
+	return len(added), goneTodos
+}
+
+// commentPicker lists the comments a scan found that are not imported
+// yet, for choosing which of them become todos.
+type commentPicker struct {
+	comments []codeComment
+	found    []codeComment
+	chosen   []bool
+	cursor   int
+	// gone is the number of open todos whose comment is gone
+	gone int
+}
+
+// pickComments shows the new comments of a scan to choose from. Without
+// any, only the todos of known comments are updated.
+func (m *TodoModel) pickComments(comments []codeComment) tea.Cmd {
+	scan := m.compareComments(comments)
+	if len(scan.found) == 0 || m.mode != browsing {
+		_, gone := m.importComments(comments, nil)
+		status := "No new comments"
+		if len(scan.found) > 0 {
+			status = fmt.Sprintf("Found %d new comments • press i to choose", len(scan.found))
+		}
+		return m.setStatus(status + goneStatus(gone))
+	}
+
+	gone := 0
+	for _, id := range scan.gone {
+		if i := m.findTodo(id); i >= 0 && !m.todos[i].Completed {
+			gone++
+		}
+	}
+	m.picker = commentPicker{
+		comments: comments,
+		found:    scan.found,
+		chosen:   make([]bool, len(scan.found)),
+		gone:     gone,
+	}
+	m.mode = pickingComments
+	m.status = ""
+	return nil
+}
+
+func goneStatus(gone int) string {
+	if gone == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" • %d no longer in the code", gone)
+}
+
+func (m TodoModel) updatePicker(msg tea.KeyMsg) (TodoModel, tea.Cmd) {
+	p := &m.picker
+	switch msg.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.found)-1 {
+			p.cursor++
+		}
+	case " ", "x":
+		p.chosen[p.cursor] = !p.chosen[p.cursor]
+	case "a":
+		// Choose all, or none when all are chosen
+		all := true
+		for _, chosen := range p.chosen {
+			all = all && chosen
+		}
+		for i := range p.chosen {
+			p.chosen[i] = !all
+		}
+	case "enter":
+		var chosen []codeComment
+		for i, comment := range p.found {
+			if p.chosen[i] {
+				chosen = append(chosen, comment)
+			}
+		}
+		added, gone := m.importComments(p.comments, chosen)
+		m.picker = commentPicker{}
+		m.mode = browsing
+		return m, m.setStatus(fmt.Sprintf("Imported %s from comments", todoCount(added)) + goneStatus(gone))
+	case "esc":
+		m.picker = commentPicker{}
+		m.mode = browsing
+	}
+	return m, nil
+}
+
+// pickerView lists the new comments with the ones chosen checked.
+func (m TodoModel) pickerView(width int) string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("205")).
+		MarginBottom(1)
+	itemStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	p := m.picker
+	chosen := 0
+	var lines []string
+	for i, comment := range p.found {
+		check := " "
+		if p.chosen[i] {
+			check = "x"
+			chosen++
+		}
+		line := fmt.Sprintf("  [%s] %s (%s:%d)", check, comment.Text, comment.File, comment.Line)
+		if i == p.cursor {
+			lines = append(lines, selectedStyle.Render("▶"+line[1:]))
+		} else {
+			lines = append(lines, itemStyle.Render(line))
+		}
+	}
+
+	// Keep the selected comment in view
+	const height = 15
+	start := 0
+	if p.cursor >= height {
+		start = p.cursor - height + 1
+	}
+	end := min(start+height, len(lines))
+
+	title := fmt.Sprintf("🔎 %d new comments · %d chosen", len(p.found), chosen)
+	if p.gone > 0 {
+		title += fmt.Sprintf(" · %d todos no longer in the code", p.gone)
+	}
+	body := lipgloss.NewStyle().Width(width).Render(strings.Join(lines[start:end], "\n"))
+	return lipgloss.JoinVertical(lipgloss.Left, titleStyle.Render(title), body)
+}
+
+// todo returns a new todo for the comment. Tokens in the comment, such as
+// a priority or tags, are taken like in a typed todo.
+func (c codeComment) todo(id int) TodoItem {
+	todo := TodoItem{
+		ID:        id,
+		CreatedAt: time.Now(),
+		Source:    todoSource{File: c.File, Line: c.Line, Text: c.Text},
+	}
+	parseTodoInput(c.Text).apply(&todo)
+	if todo.Text == "" {
+		todo.Text = c.Text
+	}
+	return todo
+}
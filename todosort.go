@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// todoSort is the order in which the todo list is shown. The todos
+// themselves are always stored in manual order.
+type todoSort int
+
+const (
+	sortManual todoSort = iota
+	sortPriority
+	sortDue
+	sortCreated
+	numSorts
+)
+
+func (s todoSort) String() string {
+	switch s {
+	case sortPriority:
+		return "priority"
+	case sortDue:
+		return "due"
+	case sortCreated:
+		return "created"
+	default:
+		return "manual"
+	}
+}
+
+func parseTodoSort(s string) (todoSort, error) {
+	for mode := sortManual; mode < numSorts; mode++ {
+		if s == mode.String() {
+			return mode, nil
+		}
+	}
+	return sortManual, fmt.Errorf("unknown sort %q (want manual, priority, due or created)", s)
+}
+
+// sortTodos returns the todos in the given order. Except in manual order,
+// completed todos go after the open ones; ties keep the manual order.
+func sortTodos(todos []TodoItem, mode todoSort) []TodoItem {
+	sorted := make([]TodoItem, len(todos))
+	copy(sorted, todos)
+	if mode == sortManual {
+		return sorted
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Completed != b.Completed {
+			return !a.Completed
+		}
+
+		switch mode {
+		case sortPriority:
+			if a.Priority.rank() != b.Priority.rank() {
+				return a.Priority.rank() > b.Priority.rank()
+			}
+			return dueBefore(a, b)
+		case sortDue:
+			if dueBefore(a, b) || dueBefore(b, a) {
+				return dueBefore(a, b)
+			}
+			return a.Priority.rank() > b.Priority.rank()
+		case sortCreated:
+			// Newest first; the ID breaks ties between todos created
+			// before creation times were recorded.
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.ID > b.ID
+		}
+		return false
+	})
+	return sorted
+}
+
+// dueBefore reports whether a is due before b. Todos without a due date
+// come last.
+func dueBefore(a, b TodoItem) bool {
+	if a.Due == "" || b.Due == "" {
+		return a.Due != "" && b.Due == ""
+	}
+	return a.Due < b.Due
+}
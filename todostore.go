@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// todoStore is where the todo list of a directory is kept. The archive is
+// always kept in pom's own data directory.
+type todoStore interface {
+	load() ([]TodoItem, error)
+	save(todos []TodoItem) error
+	// modTime is when the stored todos last changed, so that changes made
+	// by other programs can be noticed.
+	modTime() time.Time
+	name() string
+}
+
+// jsonTodoStore keeps the todos in pom's data directory, per working
+// directory. It is the default.
+type jsonTodoStore struct{}
+
+func (jsonTodoStore) load() ([]TodoItem, error)   { return loadTodosFromFile() }
+func (jsonTodoStore) save(todos []TodoItem) error { return saveTodosToFile(todos) }
+func (jsonTodoStore) name() string                { return "todos" }
+
+func (jsonTodoStore) modTime() time.Time {
+	filename, err := getSessionFilename()
+	if err != nil {
+		return time.Time{}
+	}
+	return fileModTime(filename)
+}
+
+// newTodoStore returns the store configured by the store and file settings,
+// with relative files taken from the working directory.
+func newTodoStore(cfg TodoConfig) (todoStore, error) {
+	file := cfg.File
+	switch cfg.Store {
+	case "", "json":
+		return jsonTodoStore{}, nil
+	case "todo.txt":
+		if file == "" {
+			file = "todo.txt"
+		}
+	case "markdown":
+		if file == "" {
+			file = "TODO.md"
+		}
+	default:
+		return nil, fmt.Errorf("unknown store %q (want json, todo.txt or markdown)", cfg.Store)
+	}
+
+	if !filepath.IsAbs(file) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if !insideDir(cwd, file) {
+			return nil, fmt.Errorf("file %q must be inside the working directory", file)
+		}
+		file = filepath.Join(cwd, file)
+	}
+	if cfg.Store == "markdown" {
+		return markdownTodoStore{filename: file}, nil
+	}
+	return todoTxtStore{filename: file}, nil
+}
+
+// insideDir reports whether file, relative to dir, is inside dir, also
+// when symbolic links are followed. A file that does not exist yet is
+// inside dir if its directory is.
+func insideDir(dir, file string) bool {
+	if !filepath.IsLocal(file) {
+		return false
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+
+	path := filepath.Join(dir, file)
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		if _, err := os.Lstat(path); err == nil {
+			// A link to a file that does not exist yet
+			return false
+		}
+		parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			// Without its directory the file cannot be written either
+			return os.IsNotExist(err)
+		}
+		resolved = filepath.Join(parent, filepath.Base(path))
+	} else if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// openTodoStore returns the store configured for the working directory.
+// Configuration errors are reported when pom starts, so here they fall
+// back to the default store.
+func openTodoStore() todoStore {
+	cfg, err := loadConfig()
+	if err != nil {
+		return jsonTodoStore{}
+	}
+	store, err := newTodoStore(cfg.Todos)
+	if err != nil {
+		return jsonTodoStore{}
+	}
+	return store
+}
+
+// fileModTime returns the modification time of a file, or the zero time if
+// it does not exist.
+func fileModTime(filename string) time.Time {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// todoStoreCheckInterval is how often the store is checked for changes
+// made outside of pom.
+const todoStoreCheckInterval = 2 * time.Second
+
+// todoStoreCheckMsg asks the todo list to look for changes to its store.
+type todoStoreCheckMsg struct{}
+
+func checkTodoStore() tea.Cmd {
+	return tea.Tick(todoStoreCheckInterval, func(time.Time) tea.Msg {
+		return todoStoreCheckMsg{}
+	})
+}
+
+// reloadIfChanged reloads the todos when another program changed the
+// store. Changes are picked up once the todo list is back to browsing.
+func (m *TodoModel) reloadIfChanged() tea.Cmd {
+	if m.mode != browsing || m.store.modTime().Equal(m.storeModTime) {
+		return nil
+	}
+	m.loadTodos()
+	return m.setStatus("Reloaded " + m.store.name())
+}
+
+func maxTodoID(todos []TodoItem) int {
+	maxID := 0
+	for _, todo := range todos {
+		maxID = max(maxID, todo.ID)
+	}
+	return maxID
+}
+
+// assignTodoIDs gives IDs to the todos added to the store by other
+// programs, which have none yet. Stores may give them temporary negative
+// IDs to keep track of subtasks. A todo with the text of one of known that
+// is not in todos takes its ID, so that IDs stay the same when the store
+// is read again. The other todos are numbered from nextID. It returns the
+// next free ID.
+func assignTodoIDs(todos, known []TodoItem, nextID int) int {
+	taken := map[int]bool{}
+	for _, todo := range todos {
+		taken[todo.ID] = true
+	}
+	byText := map[string][]int{}
+	for _, todo := range known {
+		if !taken[todo.ID] {
+			text := todo.inputText()
+			byText[text] = append(byText[text], todo.ID)
+		}
+	}
+
+	ids := map[int]int{}
+	for i, todo := range todos {
+		text := todo.inputText()
+		if found := byText[text]; todo.ID <= 0 && len(found) > 0 {
+			ids[todo.ID] = found[0]
+			todos[i].ID = found[0]
+			byText[text] = found[1:]
+			nextID = max(nextID, found[0]+1)
+		}
+	}
+	for i, todo := range todos {
+		if todo.ID <= 0 {
+			ids[todo.ID] = nextID
+			todos[i].ID = nextID
+			nextID++
+		}
+	}
+	for i := range todos {
+		if todos[i].ParentID < 0 {
+			todos[i].ParentID = ids[todos[i].ParentID]
+		}
+	}
+	return nextID
+}
+
+// mergeTodos combines the changes made here since the store was last read
+// or written, ours against base, with the todos another program stored in
+// the meantime, theirs. A todo changed on both sides gets the change made
+// here. Todos added here go after the todo they follow.
+func mergeTodos(base, ours, theirs []TodoItem) []TodoItem {
+	baseByID := map[int]TodoItem{}
+	for _, todo := range base {
+		baseByID[todo.ID] = todo
+	}
+	oursByID := map[int]TodoItem{}
+	for _, todo := range ours {
+		oursByID[todo.ID] = todo
+	}
+
+	merged := []TodoItem{}
+	for _, todo := range theirs {
+		if old, ok := baseByID[todo.ID]; ok {
+			mine, kept := oursByID[todo.ID]
+			if !kept {
+				// Removed here
+				continue
+			}
+			if !sameTodo(mine, old) {
+				todo = mine
+			}
+		}
+		merged = append(merged, todo)
+	}
+
+	for i, todo := range ours {
+		if indexOfTodo(merged, todo.ID) >= 0 {
+			continue
+		}
+		if old, ok := baseByID[todo.ID]; ok && sameTodo(todo, old) {
+			// Removed elsewhere and not changed here
+			continue
+		}
+		at := 0
+		for j := i - 1; j >= 0; j-- {
+			if k := indexOfTodo(merged, ours[j].ID); k >= 0 {
+				at = k + 1
+				break
+			}
+		}
+		merged = append(merged[:at], append([]TodoItem{todo}, merged[at:]...)...)
+	}
+	return merged
+}
+
+// sameTodo reports whether two versions of a todo differ in anything but
+// their position.
+func sameTodo(a, b TodoItem) bool {
+	a.Order, b.Order = 0, 0
+	return reflect.DeepEqual(a, b)
+}
+
+func indexOfTodo(todos []TodoItem, id int) int {
+	for i, todo := range todos {
+		if todo.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "bashrc"), filepath.Join(dir, "todo.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "notes")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file   string
+		inside bool
+	}{
+		{"TODO.md", true},
+		{"docs/TODO.md", true},
+		{"docs/../TODO.md", true},
+		{"../TODO.md", false},
+		{"docs/../../TODO.md", false},
+		{filepath.Join(outside, "TODO.md"), false},
+		{"todo.txt", false},
+		{"notes/TODO.md", false},
+	}
+	for _, tt := range tests {
+		if got := insideDir(dir, tt.file); got != tt.inside {
+			t.Errorf("insideDir(%q) = %v, want %v", tt.file, got, tt.inside)
+		}
+	}
+}
+
+func TestMergeTodos(t *testing.T) {
+	base := []TodoItem{
+		{ID: 1, Text: "Write tests"},
+		{ID: 2, Text: "Review draft"},
+		{ID: 3, Text: "Ship it"},
+	}
+	// edit returns base with the todo with the given ID replaced by todo,
+	// or removed if todo has no ID
+	edit := func(todos []TodoItem, id int, todo TodoItem) []TodoItem {
+		var edited []TodoItem
+		for _, t := range todos {
+			if t.ID != id {
+				edited = append(edited, t)
+			} else if todo.ID != 0 {
+				edited = append(edited, todo)
+			}
+		}
+		return edited
+	}
+
+	tests := []struct {
+		name   string
+		ours   []TodoItem
+		theirs []TodoItem
+		want   []TodoItem
+	}{
+		{
+			name:   "both edited the same todo",
+			ours:   edit(base, 2, TodoItem{ID: 2, Text: "Review draft", Completed: true}),
+			theirs: edit(base, 2, TodoItem{ID: 2, Text: "Review second draft"}),
+			want: []TodoItem{
+				{ID: 1, Text: "Write tests"},
+				{ID: 2, Text: "Review draft", Completed: true},
+				{ID: 3, Text: "Ship it"},
+			},
+		},
+		{
+			name:   "edits to different todos",
+			ours:   edit(base, 1, TodoItem{ID: 1, Text: "Write tests", Completed: true}),
+			theirs: edit(base, 3, TodoItem{ID: 3, Text: "Ship it", Priority: priorityHigh}),
+			want: []TodoItem{
+				{ID: 1, Text: "Write tests", Completed: true},
+				{ID: 2, Text: "Review draft"},
+				{ID: 3, Text: "Ship it", Priority: priorityHigh},
+			},
+		},
+		{
+			name:   "deleted here, changed there",
+			ours:   edit(base, 2, TodoItem{}),
+			theirs: edit(base, 2, TodoItem{ID: 2, Text: "Review second draft"}),
+			want: []TodoItem{
+				{ID: 1, Text: "Write tests"},
+				{ID: 3, Text: "Ship it"},
+			},
+		},
+		{
+			name:   "changed here, deleted there",
+			ours:   edit(base, 2, TodoItem{ID: 2, Text: "Review draft", Completed: true}),
+			theirs: edit(base, 2, TodoItem{}),
+			want: []TodoItem{
+				{ID: 1, Text: "Write tests"},
+				{ID: 2, Text: "Review draft", Completed: true},
+				{ID: 3, Text: "Ship it"},
+			},
+		},
+		{
+			name:   "deleted there, unchanged here",
+			ours:   base,
+			theirs: edit(base, 1, TodoItem{}),
+			want: []TodoItem{
+				{ID: 2, Text: "Review draft"},
+				{ID: 3, Text: "Ship it"},
+			},
+		},
+		{
+			name: "added on both sides",
+			ours: []TodoItem{
+				{ID: 1, Text: "Write tests"},
+				{ID: 4, Text: "Fix flaky test"},
+				{ID: 2, Text: "Review draft"},
+				{ID: 3, Text: "Ship it"},
+			},
+			theirs: append(append([]TodoItem{}, base...), TodoItem{ID: 5, Text: "Added on the phone"}),
+			want: []TodoItem{
+				{ID: 1, Text: "Write tests"},
+				{ID: 4, Text: "Fix flaky test"},
+				{ID: 2, Text: "Review draft"},
+				{ID: 3, Text: "Ship it"},
+				{ID: 5, Text: "Added on the phone"},
+			},
+		},
+		{
+			name: "moved here",
+			ours: []TodoItem{base[2], base[0], base[1]},
+			// Only the position changed, so the order there is kept
+			theirs: base,
+			want:   base,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeTodos(base, tt.ours, tt.theirs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if !sameTodo(got[i], tt.want[i]) {
+					t.Errorf("todo %d is %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestSaveMergesChangesMadeElsewhere edits the file behind pom's back
+// before pom saves a change of its own.
+func TestSaveMergesChangesMadeElsewhere(t *testing.T) {
+	m, store := markdownModel(t, markdownFile(
+		"- [ ] Write tests <!-- pom id:1 -->",
+		"- [ ] Review draft <!-- pom id:2 -->",
+		"- [ ] Ship it <!-- pom id:3 -->",
+	))
+
+	edited := markdownFile(
+		"- [ ] Write tests <!-- pom id:1 -->",
+		"- [ ] Review second draft <!-- pom id:2 -->",
+		"- [ ] Added in an editor",
+	)
+	if err := os.WriteFile(store.filename, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the change is seen even on coarse file system clocks
+	m.storeModTime = m.storeModTime.Add(-time.Second)
+
+	m.updateTodo(m.findTodo(2), "Review draft !high")
+	m.addTodo("Tag the release")
+
+	data, err := os.ReadFile(store.filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := markdownFile(
+		"- [ ] Write tests <!-- pom id:1 -->",
+		"- [ ] Review draft !high <!-- pom id:2 -->",
+		"- [ ] Added in an editor <!-- pom id:4 -->",
+		"- [ ] Tag the release <!-- pom id:5 created:"+time.Now().Format(dueLayout)+" -->",
+	)
+	if got := string(data); got != want {
+		t.Errorf("saved\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// isTag reports whether a word is a +project or @context tag.
+func isTag(word string) bool {
+	if len(word) < 2 || (word[0] != '+' && word[0] != '@') {
+		return false
+	}
+	return !strings.ContainsAny(word[1:], "+@")
+}
+
+// tags returns the +project and @context tags of the todo, in order.
+func (t TodoItem) tags() []string {
+	tags := append([]string{}, t.Projects...)
+	return append(tags, t.Contexts...)
+}
+
+// hasTag reports whether the todo has the tag, ignoring case.
+func (t TodoItem) hasTag(tag string) bool {
+	for _, own := range t.tags() {
+		if strings.EqualFold(own, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTodos is the list filter for todos. Tags in the filter must match
+// a tag of the todo exactly; the rest of the filter is matched fuzzily
+// against the text.
+func filterTodos(term string, targets []string) []list.Rank {
+	var tags, words []string
+	for _, word := range strings.Fields(term) {
+		if isTag(word) {
+			tags = append(tags, word)
+		} else {
+			words = append(words, word)
+		}
+	}
+
+	var ranks []list.Rank
+	if len(words) == 0 {
+		for i := range targets {
+			ranks = append(ranks, list.Rank{Index: i})
+		}
+	} else {
+		ranks = list.DefaultFilter(strings.Join(words, " "), targets)
+	}
+
+	var matched []list.Rank
+	for _, rank := range ranks {
+		todo := TodoItem{}
+		parseTodoInput(targets[rank.Index]).apply(&todo)
+		keep := true
+		for _, tag := range tags {
+			if !todo.hasTag(tag) {
+				keep = false
+			}
+		}
+		if keep {
+			matched = append(matched, rank)
+		}
+	}
+	return matched
+}
+
+// tagCounts returns the tags of the open todos with the number of open
+// todos carrying each, projects before contexts and otherwise sorted.
+func tagCounts(todos []TodoItem) ([]string, map[string]int) {
+	counts := map[string]int{}
+	for _, todo := range todos {
+		if todo.Completed || todo.scheduled(time.Now()) {
+			continue
+		}
+		for _, tag := range todo.tags() {
+			counts[strings.ToLower(tag)]++
+		}
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i][0] != tags[j][0] {
+			return tags[i][0] == '+'
+		}
+		return tags[i] < tags[j]
+	})
+	return tags, counts
+}
+
+// nextTagFilter cycles the list filter through the tags of the open todos
+// and back to showing everything.
+func (m *TodoModel) nextTagFilter() {
+	tags, _ := tagCounts(m.todos)
+	current := strings.ToLower(m.list.FilterValue())
+	if !m.list.IsFiltered() {
+		current = ""
+	}
+
+	next := 0
+	for i, tag := range tags {
+		if tag == current {
+			next = i + 1
+		}
+	}
+	if next >= len(tags) {
+		m.list.ResetFilter()
+		return
+	}
+	m.list.SetFilterText(tags[next])
+}
+
+// tagBar shows the tags with their number of open todos, highlighting the
+// one the list is filtered by.
+func (m TodoModel) tagBar(width int) string {
+	tags, counts := tagCounts(m.todos)
+	if len(tags) == 0 {
+		return ""
+	}
+
+	tagStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	activeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+
+	active := ""
+	if m.list.IsFiltered() {
+		active = strings.ToLower(m.list.FilterValue())
+	}
+
+	var parts []string
+	for _, tag := range tags {
+		style := tagStyle
+		if tag == active {
+			style = activeStyle
+		}
+		parts = append(parts, style.Render(fmt.Sprintf("%s(%d)", tag, counts[tag])))
+	}
+
+	return lipgloss.NewStyle().
+		Width(width).
+		Align(lipgloss.Center).
+		Render(strings.Join(parts, "  "))
+}
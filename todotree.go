@@ -0,0 +1,173 @@
+package main
+
+// Subtasks are stored in the flat todo list with the ID of their parent,
+// and arranged into a tree for display.
+
+// hasParent reports whether the todo is a subtask of another todo in the
+// list. Todos whose parent is gone, or whose parents form a loop, are
+// shown at the top level.
+func hasParent(byID map[int]TodoItem, todo TodoItem) bool {
+	parent := todo.ParentID
+	for steps := 0; parent != 0; steps++ {
+		next, ok := byID[parent]
+		if !ok || parent == todo.ID || steps > len(byID) {
+			return false
+		}
+		parent = next.ParentID
+	}
+	return todo.ParentID != 0
+}
+
+// arrangeTodos returns the todos in the given order with every subtask
+// right below its parent, siblings kept in that order. Subtasks of
+// collapsed todos are left out unless expandAll is set.
+func arrangeTodos(todos []TodoItem, mode todoSort, expandAll bool) []TodoItem {
+	byID := map[int]TodoItem{}
+	for _, todo := range todos {
+		byID[todo.ID] = todo
+	}
+
+	var roots []TodoItem
+	children := map[int][]TodoItem{}
+	for _, todo := range sortTodos(todos, mode) {
+		if hasParent(byID, todo) {
+			children[todo.ParentID] = append(children[todo.ParentID], todo)
+		} else {
+			roots = append(roots, todo)
+		}
+	}
+
+	var arranged []TodoItem
+	var add func(todo TodoItem, depth int)
+	add = func(todo TodoItem, depth int) {
+		todo.depth = depth
+		for _, child := range children[todo.ID] {
+			todo.subtasks++
+			if child.Completed {
+				todo.subtasksDone++
+			}
+		}
+		arranged = append(arranged, todo)
+
+		if todo.Collapsed && !expandAll {
+			return
+		}
+		for _, child := range children[todo.ID] {
+			add(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		add(root, 0)
+	}
+	return arranged
+}
+
+// subtreeIDs returns the ID of the todo and of all its subtasks.
+func subtreeIDs(todos []TodoItem, id int) map[int]bool {
+	ids := map[int]bool{id: true}
+	for changed := true; changed; {
+		changed = false
+		for _, todo := range todos {
+			if ids[todo.ParentID] && !ids[todo.ID] {
+				ids[todo.ID] = true
+				changed = true
+			}
+		}
+	}
+	return ids
+}
+
+// indentTodo makes a todo a subtask of the sibling shown above it.
+func (m *TodoModel) indentTodo(index int) {
+	if index < 0 || index >= len(m.todos) {
+		return
+	}
+
+	visible := m.visibleTodos()
+	for i := len(visible) - 1; i >= 0; i-- {
+		if visible[i].ID != m.todos[index].ID {
+			continue
+		}
+		for j := i - 1; j >= 0 && visible[j].depth >= visible[i].depth; j-- {
+			if visible[j].depth == visible[i].depth {
+				m.remember("indent", m.todos[index])
+				m.todos[index].ParentID = visible[j].ID
+				m.todos[m.findTodo(visible[j].ID)].Collapsed = false
+				m.updateList()
+				m.saveTodos()
+				return
+			}
+		}
+	}
+}
+
+// outdentTodo moves a subtask up a level, placing it right after its
+// former parent.
+func (m *TodoModel) outdentTodo(index int) {
+	if index < 0 || index >= len(m.todos) || m.todos[index].ParentID == 0 {
+		return
+	}
+
+	m.remember("outdent", m.todos[index])
+	todo := m.todos[index]
+	parentID := todo.ParentID
+	todo.ParentID = 0
+	if parent := m.findTodo(parentID); parent >= 0 {
+		todo.ParentID = m.todos[parent].ParentID
+	}
+
+	m.todos = append(m.todos[:index], m.todos[index+1:]...)
+	at := index
+	if parent := m.findTodo(parentID); parent >= 0 {
+		at = parent + 1
+	}
+	m.todos = append(m.todos[:at], append([]TodoItem{todo}, m.todos[at:]...)...)
+	m.updateList()
+	m.saveTodos()
+}
+
+// toggleCollapsed hides or shows the subtasks of a todo.
+func (m *TodoModel) toggleCollapsed(index int) {
+	if index < 0 || index >= len(m.todos) {
+		return
+	}
+	for _, todo := range m.todos {
+		if todo.ParentID == m.todos[index].ID {
+			m.todos[index].Collapsed = !m.todos[index].Collapsed
+			m.updateList()
+			m.saveTodos()
+			return
+		}
+	}
+}
+
+// updateParents completes the parents of a todo whose subtasks are now
+// all done, and reopens completed parents of a reopened subtask. It
+// returns the indexes of the parents that changed.
+func (m *TodoModel) updateParents(id int) []int {
+	var changed []int
+	for steps := 0; steps < len(m.todos); steps++ {
+		i := m.findTodo(id)
+		if i < 0 || m.todos[i].ParentID == 0 {
+			break
+		}
+		parent := m.findTodo(m.todos[i].ParentID)
+		if parent < 0 {
+			break
+		}
+
+		done := true
+		for _, todo := range m.todos {
+			if todo.ParentID == m.todos[parent].ID && !todo.Completed {
+				done = false
+			}
+		}
+		if m.todos[parent].Completed == done {
+			break
+		}
+		m.todos[parent].Completed = done
+		changed = append(changed, parent)
+		id = m.todos[parent].ID
+	}
+	return changed
+}
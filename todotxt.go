@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Todos are written to todo.txt (http://todotxt.org) files one per line:
+//
+//	x 2025-03-14 2025-03-10 Send invoice +acme @email due:2025-03-14 pri:A id:4
+//	(B) 2025-03-12 Review draft +acme est:2 id:5 parent:4
+//
+// Priorities high, medium and low become (A), (B) and (C). The attributes
+// todo.txt has no syntax for are written as key:value pairs, which other
+// todo.txt tools keep as they are.
+
+// todoTxtStore keeps the todos in a todo.txt file, for sharing them with
+// other todo.txt tools.
+type todoTxtStore struct {
+	filename string
+}
+
+func (s todoTxtStore) load() ([]TodoItem, error) {
+	file, err := os.Open(s.filename)
+	if os.IsNotExist(err) {
+		return []TodoItem{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	todos, err := readTodoTxt(file)
+	if err != nil {
+		return nil, err
+	}
+	// The file is in manual order
+	for i := range todos {
+		todos[i].Order = i + 1
+	}
+	return todos, nil
+}
+
+func (s todoTxtStore) save(todos []TodoItem) error {
+	return os.WriteFile(s.filename, []byte(formatTodoTxt(todos)), 0644)
+}
+
+func (s todoTxtStore) modTime() time.Time { return fileModTime(s.filename) }
+func (s todoTxtStore) name() string       { return filepath.Base(s.filename) }
+
+// readTodoTxt parses a todo.txt file. Todos without an id: get ID 0.
+func readTodoTxt(r io.Reader) ([]TodoItem, error) {
+	todos := []TodoItem{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if todo, ok := parseTodoTxtLine(scanner.Text(), time.Now()); ok {
+			todos = append(todos, todo)
+		}
+	}
+	return todos, scanner.Err()
+}
+
+func formatTodoTxt(todos []TodoItem) string {
+	var b strings.Builder
+	for _, todo := range todos {
+		b.WriteString(todo.todoTxt())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// parseTodoTxtLine parses a line of a todo.txt file. Blank lines are not
+// todos.
+func parseTodoTxtLine(line string, now time.Time) (TodoItem, bool) {
+	var todo TodoItem
+	words := strings.Fields(line)
+	if len(words) > 0 && words[0] == "x" {
+		todo.Completed = true
+		words = words[1:]
+		if date, ok := parseTodoTxtDate(words); ok {
+			todo.CompletedAt = date
+			words = words[1:]
+		}
+	} else if len(words) > 0 {
+		if p, ok := parseTodoTxtPriority(words[0]); ok {
+			todo.Priority = p
+			words = words[1:]
+		}
+	}
+	if date, ok := parseTodoTxtDate(words); ok {
+		todo.CreatedAt = date
+		words = words[1:]
+	}
+
+	var text []string
+	for _, word := range words {
+		if todo.setTodoTxtAttribute(word, now) {
+			continue
+		}
+		if isTag(word) && word[0] == '+' {
+			todo.Projects = append(todo.Projects, word)
+		} else if isTag(word) {
+			todo.Contexts = append(todo.Contexts, word)
+		}
+		text = append(text, word)
+	}
+	todo.Text = strings.Join(text, " ")
+	return todo, todo.Text != ""
+}
+
+// parseTodoTxtDate parses the first word as a YYYY-MM-DD date.
+func parseTodoTxtDate(words []string) (time.Time, bool) {
+	if len(words) == 0 {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(dueLayout, words[0], time.Local)
+	return date, err == nil
+}
+
+// parseTodoTxtPriority parses a priority such as "(A)". Priorities below
+// (C) are all low.
+func parseTodoTxtPriority(word string) (priority, bool) {
+	if len(word) != 3 || word[0] != '(' || word[2] != ')' || word[1] < 'A' || word[1] > 'Z' {
+		return priorityNone, false
+	}
+	switch word[1] {
+	case 'A':
+		return priorityHigh, true
+	case 'B':
+		return priorityMedium, true
+	default:
+		return priorityLow, true
+	}
+}
+
+func (p priority) todoTxt() string {
+	switch p {
+	case priorityHigh:
+		return "A"
+	case priorityMedium:
+		return "B"
+	case priorityLow:
+		return "C"
+	default:
+		return ""
+	}
+}
+
+// setTodoTxtAttribute sets the attribute given by a key:value word, and
+// reports whether the word was one.
+func (t *TodoItem) setTodoTxtAttribute(word string, now time.Time) bool {
+	key, value, ok := strings.Cut(word, ":")
+	if !ok || value == "" {
+		return false
+	}
+
+	switch strings.ToLower(key) {
+	case "due":
+		if due, ok := parseDateToken(word, "due:", now); ok {
+			t.Due = due.Format(dueLayout)
+			return true
+		}
+	case "t":
+		if date, ok := parseDateToken(word, "t:", now); ok {
+			t.Scheduled = date.Format(dueLayout)
+			return true
+		}
+	case "rec":
+		// Other tools mark recurrences counted from the due date with a +
+		if rule, ok := parseRecurrence(strings.TrimPrefix(value, "+")); ok {
+			t.Recur = rule
+			return true
+		}
+	case "pri":
+		if p, ok := parseTodoTxtPriority("(" + strings.ToUpper(value) + ")"); ok {
+			t.Priority = p
+			return true
+		}
+	case "id":
+		return parseTodoTxtNumber(value, &t.ID)
+	case "parent":
+		return parseTodoTxtNumber(value, &t.ParentID)
+	case "est":
+		return parseTodoTxtNumber(value, &t.Estimate)
+	case "pomos":
+		return parseTodoTxtNumber(value, &t.Pomodoros)
+	case "spent":
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			t.FocusSeconds = int(d.Seconds())
+			return true
+		}
+	case "src":
+		file, line, ok := strings.Cut(value, ":")
+		if ok && file != "" && parseTodoTxtNumber(line, &t.Source.Line) {
+			t.Source.File = file
+			return true
+		}
+	case "focus":
+		return parseTodoTxtFlag(value, &t.Focus)
+	case "fold":
+		return parseTodoTxtFlag(value, &t.Collapsed)
+	}
+	return false
+}
+
+// parseTodoTxtFlag parses a yes or no value. Other values are not pom's,
+// so the word stays in the text.
+func parseTodoTxtFlag(value string, flag *bool) bool {
+	switch value {
+	case "yes":
+		*flag = true
+	case "no":
+		*flag = false
+	default:
+		return false
+	}
+	return true
+}
+
+func parseTodoTxtNumber(value string, n *int) bool {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return false
+	}
+	*n = parsed
+	return true
+}
+
+// todoTxt returns the todo as a line of a todo.txt file.
+func (t TodoItem) todoTxt() string {
+	var words []string
+	if t.Completed {
+		words = append(words, "x")
+		if !t.CompletedAt.IsZero() {
+			words = append(words, t.CompletedAt.Format(dueLayout))
+		}
+	} else if p := t.Priority.todoTxt(); p != "" {
+		words = append(words, "("+p+")")
+	}
+	// A single date after x is the completion date
+	if !t.CreatedAt.IsZero() && (!t.Completed || !t.CompletedAt.IsZero()) {
+		words = append(words, t.CreatedAt.Format(dueLayout))
+	}
+	words = append(words, t.Text)
+
+	// Completed todos lose their priority in todo.txt, so it is kept as
+	// an attribute
+	if p := t.Priority.todoTxt(); t.Completed && p != "" {
+		words = append(words, "pri:"+p)
+	}
+	if t.Due != "" {
+		words = append(words, "due:"+t.Due)
+	}
+	if t.Recur != "" {
+		words = append(words, "rec:"+t.Recur)
+	}
+	if t.Scheduled != "" {
+		words = append(words, "t:"+t.Scheduled)
+	}
+	if t.Estimate > 0 {
+		words = append(words, fmt.Sprintf("est:%d", t.Estimate))
+	}
+	if t.Pomodoros > 0 {
+		words = append(words, fmt.Sprintf("pomos:%d", t.Pomodoros))
+	}
+	if t.FocusSeconds > 0 {
+		words = append(words, "spent:"+shortDuration(time.Duration(t.FocusSeconds)*time.Second))
+	}
+	if t.Source.File != "" && !strings.ContainsAny(t.Source.File, " :") {
+		words = append(words, "src:"+t.Source.String())
+	}
+	if t.Focus {
+		words = append(words, "focus:yes")
+	}
+	if t.Collapsed {
+		words = append(words, "fold:yes")
+	}
+	if t.ID > 0 {
+		words = append(words, fmt.Sprintf("id:%d", t.ID))
+	}
+	if t.ParentID > 0 {
+		words = append(words, fmt.Sprintf("parent:%d", t.ParentID))
+	}
+	return strings.Join(words, " ")
+}
+
+// importTodos adds todos read from elsewhere to the end of the list with
+// new IDs, keeping subtasks under their parents. It returns how many were
+// added.
+func (m *TodoModel) importTodos(todos []TodoItem) int {
+	first := m.nextID
+	ids := map[int]int{}
+	for i, todo := range todos {
+		if todo.ID > 0 {
+			ids[todo.ID] = first + i
+		}
+	}
+	m.nextID += len(todos)
+
+	for i, todo := range todos {
+		todo.ID = first + i
+		todo.ParentID = ids[todo.ParentID]
+		// The focus todo stays the one chosen here
+		todo.Focus = false
+		if todo.CreatedAt.IsZero() {
+			todo.CreatedAt = time.Now()
+		}
+		m.todos = append(m.todos, todo)
+	}
+
+	m.updateList()
+	m.saveTodos()
+	return len(todos)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTodoTxtFlags(t *testing.T) {
+	tests := []struct {
+		line      string
+		text      string
+		focus     bool
+		collapsed bool
+	}{
+		{"Write report focus:yes", "Write report", true, false},
+		{"Write report fold:yes", "Write report", false, true},
+		{"Write report focus:no fold:no", "Write report", false, false},
+		// Values pom does not write are the user's text
+		{"Join focus:group", "Join focus:group", false, false},
+		{"Do fold:laundry", "Do fold:laundry", false, false},
+	}
+	for _, tt := range tests {
+		todo, ok := parseTodoTxtLine(tt.line, time.Now())
+		if !ok {
+			t.Errorf("%q is not a todo", tt.line)
+			continue
+		}
+		if todo.Text != tt.text || todo.Focus != tt.focus || todo.Collapsed != tt.collapsed {
+			t.Errorf("%q: got text %q, focus %v, collapsed %v", tt.line, todo.Text, todo.Focus, todo.Collapsed)
+		}
+		if again, _ := parseTodoTxtLine(todo.todoTxt(), time.Now()); again.Text != tt.text {
+			t.Errorf("%q: saved as %q, which reads back as %q", tt.line, todo.todoTxt(), again.Text)
+		}
+	}
+}
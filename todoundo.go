@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxUndo is how many changes to the todo list can be undone.
+const maxUndo = 100
+
+// todoSnapshot is the todo list and archive as they were before a change,
+// with a description of the change such as `delete "Write report"`.
+type todoSnapshot struct {
+	todos   []TodoItem
+	archive []TodoItem
+	change  string
+}
+
+// todoStatusClearMsg hides the status message with the given id.
+type todoStatusClearMsg struct {
+	id int
+}
+
+// remember records the todo list before a change so it can be undone. A
+// new change cannot be redone after undoing others.
+func (m *TodoModel) remember(change string, todo TodoItem) {
+	m.rememberChange(fmt.Sprintf("%s %q", change, todo.Text))
+}
+
+// rememberChange is remember for changes that are not about a single todo.
+func (m *TodoModel) rememberChange(change string) {
+	m.undo = append(m.undo, m.snapshot(change))
+	if len(m.undo) > maxUndo {
+		m.undo = m.undo[len(m.undo)-maxUndo:]
+	}
+	m.redo = nil
+}
+
+func (m TodoModel) snapshot(change string) todoSnapshot {
+	todos := make([]TodoItem, len(m.todos))
+	copy(todos, m.todos)
+	archive := make([]TodoItem, len(m.archive))
+	copy(archive, m.archive)
+	return todoSnapshot{todos: todos, archive: archive, change: change}
+}
+
+// restore replaces the todo list with a snapshot. Pomodoros and focused
+// time are not changes the user made, so they are kept.
+func (m *TodoModel) restore(s todoSnapshot) {
+	credit := map[int]TodoItem{}
+	for _, todo := range m.todos {
+		credit[todo.ID] = todo
+	}
+
+	m.todos = make([]TodoItem, len(s.todos))
+	copy(m.todos, s.todos)
+	for i, todo := range m.todos {
+		if current, ok := credit[todo.ID]; ok {
+			m.todos[i].Pomodoros = current.Pomodoros
+			m.todos[i].FocusSeconds = current.FocusSeconds
+		}
+	}
+	m.archive = make([]TodoItem, len(s.archive))
+	copy(m.archive, s.archive)
+	m.updateList()
+	m.saveTodos()
+	m.saveArchive()
+}
+
+// undoChange reverts the last change to the todo list.
+func (m *TodoModel) undoChange() tea.Cmd {
+	if len(m.undo) == 0 {
+		return m.setStatus("Nothing to undo")
+	}
+
+	last := m.undo[len(m.undo)-1]
+	m.undo = m.undo[:len(m.undo)-1]
+	m.redo = append(m.redo, m.snapshot(last.change))
+	m.restore(last)
+	return m.setStatus("Undid " + last.change)
+}
+
+// redoChange applies the last undone change again.
+func (m *TodoModel) redoChange() tea.Cmd {
+	if len(m.redo) == 0 {
+		return m.setStatus("Nothing to redo")
+	}
+
+	last := m.redo[len(m.redo)-1]
+	m.redo = m.redo[:len(m.redo)-1]
+	m.undo = append(m.undo, m.snapshot(last.change))
+	m.restore(last)
+	return m.setStatus("Redid " + last.change)
+}
+
+// setStatus shows a message below the todo list for a few seconds.
+func (m *TodoModel) setStatus(status string) tea.Cmd {
+	m.status = status
+	m.statusID++
+	id := m.statusID
+	return tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+		return todoStatusClearMsg{id}
+	})
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// WebhookConfig is one endpoint receiving events as HTTP POSTs.
+type WebhookConfig struct {
+	URL     string            `toml:"url"`
+	Events  []string          `toml:"events"`
+	Headers map[string]string `toml:"headers"`
+}
+
+// webhookEventNames lists the events that can be sent to webhooks.
+var webhookEventNames = []string{"session.start", "session.complete", "session.skip", "todo.complete"}
+
+func (c WebhookConfig) validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url %q must be an http or https URL", c.URL)
+	}
+	for _, event := range c.Events {
+		known := false
+		for _, name := range webhookEventNames {
+			if event == name {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("webhook %s: unknown event %q", c.URL, event)
+		}
+	}
+	return nil
+}
+
+// wants reports whether the webhook subscribed to event. Webhooks without
+// an event list receive every event.
+func (c WebhookConfig) wants(event string) bool {
+	if len(c.Events) == 0 {
+		return true
+	}
+	for _, e := range c.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookPayload is the JSON body posted to webhooks. Events may be
+// delivered more than once; receivers can use ID to ignore duplicates.
+type webhookPayload struct {
+	ID      string          `json:"id"`
+	Event   string          `json:"event"`
+	Time    time.Time       `json:"time"`
+	Dir     string          `json:"dir"`
+	Session *webhookSession `json:"session,omitempty"`
+	Todo    *webhookTodo    `json:"todo,omitempty"`
+}
+
+type webhookSession struct {
+	Type             string `json:"type"`
+	PlannedSeconds   int    `json:"planned_seconds"`
+	ElapsedSeconds   int    `json:"elapsed_seconds"`
+	RemainingSeconds int    `json:"remaining_seconds"`
+	SessionCount     int    `json:"session_count"`
+}
+
+type webhookTodo struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+type Webhooks struct {
+	endpoints []WebhookConfig
+}
+
+func NewWebhooks(cfg []WebhookConfig) Webhooks {
+	return Webhooks{endpoints: cfg}
+}
+
+func newEventID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sessionEvents returns the webhook payloads for timer events. Only the
+// start and end of sessions are sent. Sessions skipped before they started
+// and sessions ended early are both skipped.
+func sessionEvents(events []timerEvent) []webhookPayload {
+	var payloads []webhookPayload
+	for _, ev := range events {
+		var name string
+		switch ev.Event {
+		case "work_start", "break_start":
+			name = "session.start"
+		case "work_skip", "break_skip":
+			name = "session.skip"
+		case "work_end", "break_end":
+			name = "session.skip"
+			if ev.Completed {
+				name = "session.complete"
+			}
+		default:
+			continue
+		}
+
+		payloads = append(payloads, webhookPayload{
+			ID:    newEventID(),
+			Event: name,
+			Time:  ev.Time,
+			Dir:   ev.Dir,
+			Session: &webhookSession{
+				Type:             ev.Session,
+				PlannedSeconds:   ev.Planned,
+				ElapsedSeconds:   ev.Elapsed,
+				RemainingSeconds: ev.Remaining,
+				SessionCount:     ev.SessionCount,
+			},
+		})
+	}
+	return payloads
+}
+
+func todoCompletedEvent(todo TodoItem) webhookPayload {
+	dir, _ := os.Getwd()
+	return webhookPayload{
+		ID:    newEventID(),
+		Event: "todo.complete",
+		Time:  time.Now(),
+		Dir:   dir,
+		Todo:  &webhookTodo{ID: todo.ID, Text: todo.Text},
+	}
+}
+
+// send queues the payloads for every webhook that wants them and returns a
+// command delivering the outbox.
+func (w Webhooks) send(payloads ...webhookPayload) tea.Cmd {
+	var entries []outboxEntry
+	for _, payload := range payloads {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			continue
+		}
+		for i, endpoint := range w.endpoints {
+			if !endpoint.wants(payload.Event) {
+				continue
+			}
+			entries = append(entries, outboxEntry{
+				ID:      fmt.Sprintf("%s-%d", payload.ID, i),
+				URL:     endpoint.URL,
+				Headers: endpoint.Headers,
+				Payload: data,
+			})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := enqueueOutbox(entries); err != nil {
+		return nil
+	}
+	return flushOutboxCmd()
+}
+
+// outboxEntry is a delivery of one payload to one webhook that has not
+// succeeded yet. The outbox is persisted so that events created while
+// offline, or while pom is not running, are delivered later.
+type outboxEntry struct {
+	ID          string            `json:"id"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Payload     json.RawMessage   `json:"payload"`
+	Attempts    int               `json:"attempts"`
+	NextAttempt time.Time         `json:"next_attempt"`
+	LastError   string            `json:"last_error,omitempty"`
+}
+
+const maxWebhookBackoff = time.Hour
+
+// backoff returns how long to wait before retrying after the given number
+// of failed attempts: 5s, 10s, 20s, ... up to an hour.
+func backoff(attempts int) time.Duration {
+	delay := 5 * time.Second
+	for i := 1; i < attempts && delay < maxWebhookBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxWebhookBackoff {
+		delay = maxWebhookBackoff
+	}
+	return delay
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// outboxMu serialises flushes within this process; the flush lock file does
+// the same across processes.
+var outboxMu sync.Mutex
+
+func getOutboxFilename() (string, error) {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "outbox.json"), nil
+}
+
+// lockFile takes an exclusive lock on the named file in the data
+// directory. With wait unset it fails instead of waiting for the lock.
+func lockFile(name string, wait bool) (*os.File, error) {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dataDir, name), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func unlockFile(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	f.Close()
+}
+
+// updateOutbox loads the outbox, applies update and saves the result while
+// holding the outbox lock.
+func updateOutbox(update func(entries []outboxEntry) []outboxEntry) error {
+	lock, err := lockFile("outbox.lock", true)
+	if err != nil {
+		return err
+	}
+	defer unlockFile(lock)
+
+	filename, err := getOutboxFilename()
+	if err != nil {
+		return err
+	}
+
+	entries := []outboxEntry{}
+	if data, err := ioutil.ReadFile(filename); err == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return err
+		}
+	}
+
+	entries = update(entries)
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+func enqueueOutbox(added []outboxEntry) error {
+	return updateOutbox(func(entries []outboxEntry) []outboxEntry {
+		return append(entries, added...)
+	})
+}
+
+// deliver posts one entry. Permanent failures (client errors other than
+// timeouts and rate limiting) are reported as such so they are not retried.
+func deliver(entry outboxEntry) (permanent bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, entry.URL, bytes.NewReader(entry.Payload))
+	if err != nil {
+		return true, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pom")
+	for name, value := range entry.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("%s returned %s", entry.URL, resp.Status)
+	permanent = resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests
+	return permanent, err
+}
+
+// flushOutbox delivers every entry that is due and returns when the next
+// retry is due, or the zero time if nothing is left to retry.
+func flushOutbox(now time.Time) (time.Time, error) {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+
+	lock, err := lockFile("outbox.flush.lock", false)
+	if err != nil {
+		// Another process is delivering the outbox
+		return time.Time{}, nil
+	}
+	defer unlockFile(lock)
+
+	var due []outboxEntry
+	err = updateOutbox(func(entries []outboxEntry) []outboxEntry {
+		for _, entry := range entries {
+			if !entry.NextAttempt.After(now) {
+				due = append(due, entry)
+			}
+		}
+		return entries
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Deliver without holding the outbox lock so events can still be queued
+	done := map[string]bool{}
+	failed := map[string]outboxEntry{}
+	for _, entry := range due {
+		permanent, err := deliver(entry)
+		if err == nil || permanent {
+			done[entry.ID] = true
+			continue
+		}
+		entry.Attempts++
+		entry.NextAttempt = time.Now().Add(backoff(entry.Attempts))
+		entry.LastError = err.Error()
+		failed[entry.ID] = entry
+	}
+
+	var next time.Time
+	err = updateOutbox(func(entries []outboxEntry) []outboxEntry {
+		remaining := []outboxEntry{}
+		for _, entry := range entries {
+			if done[entry.ID] {
+				continue
+			}
+			if updated, ok := failed[entry.ID]; ok {
+				entry = updated
+			}
+			if next.IsZero() || entry.NextAttempt.Before(next) {
+				next = entry.NextAttempt
+			}
+			remaining = append(remaining, entry)
+		}
+		return remaining
+	})
+	return next, err
+}
+
+// outboxFlushMsg asks for the outbox to be delivered.
+type outboxFlushMsg struct{}
+
+// outboxFlushedMsg reports when the outbox should be delivered again.
+type outboxFlushedMsg struct {
+	next time.Time
+}
+
+func flushOutboxCmd() tea.Cmd {
+	return func() tea.Msg {
+		next, _ := flushOutbox(time.Now())
+		return outboxFlushedMsg{next: next}
+	}
+}
+
+// outboxRetrier keeps a single retry of the outbox scheduled in a running
+// program, however many deliveries were started.
+type outboxRetrier struct {
+	retryAt time.Time
+}
+
+func (r *outboxRetrier) Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case outboxFlushMsg:
+		r.retryAt = time.Time{}
+		return flushOutboxCmd()
+	case outboxFlushedMsg:
+		if msg.next.IsZero() || (!r.retryAt.IsZero() && !msg.next.Before(r.retryAt)) {
+			return nil
+		}
+		r.retryAt = msg.next
+		return tea.Tick(time.Until(msg.next), func(time.Time) tea.Msg {
+			return outboxFlushMsg{}
+		})
+	}
+	return nil
+}
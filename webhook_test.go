@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{10, 2560 * time.Second},
+		{11, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+		failed    bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusNoContent, false, false},
+		{http.StatusBadRequest, true, true},
+		{http.StatusNotFound, true, true},
+		{http.StatusRequestTimeout, false, true},
+		{http.StatusTooManyRequests, false, true},
+		{http.StatusInternalServerError, false, true},
+		{http.StatusServiceUnavailable, false, true},
+	}
+	for _, tt := range tests {
+		var header, contentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Get("X-Token")
+			contentType = r.Header.Get("Content-Type")
+			w.WriteHeader(tt.status)
+		}))
+
+		permanent, err := deliver(outboxEntry{
+			ID:      "a-0",
+			URL:     server.URL,
+			Headers: map[string]string{"X-Token": "secret"},
+			Payload: json.RawMessage(`{"event":"session.start"}`),
+		})
+		server.Close()
+
+		if permanent != tt.permanent || (err != nil) != tt.failed {
+			t.Errorf("status %d: got permanent %v, error %v", tt.status, permanent, err)
+		}
+		if header != "secret" || contentType != "application/json" {
+			t.Errorf("status %d: got headers %q and %q", tt.status, header, contentType)
+		}
+	}
+}
+
+func TestDeliverUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	permanent, err := deliver(outboxEntry{ID: "a-0", URL: url, Payload: json.RawMessage(`{}`)})
+	if err == nil || permanent {
+		t.Errorf("got permanent %v, error %v; want a retryable error", permanent, err)
+	}
+}
+
+// readOutbox returns the entries left in the outbox.
+func readOutbox(t *testing.T) []outboxEntry {
+	t.Helper()
+	filename, err := getOutboxFilename()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []outboxEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestFlushOutboxRetries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// The server fails twice before accepting the event
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if err := enqueueOutbox([]outboxEntry{{ID: "a-0", URL: server.URL, Payload: json.RawMessage(`{}`)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	next, err := flushOutbox(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := readOutbox(t)
+	if len(entries) != 1 || entries[0].Attempts != 1 || entries[0].LastError == "" {
+		t.Fatalf("after the first failure the outbox is %+v", entries)
+	}
+	if delay := next.Sub(now); delay < 5*time.Second || delay > 6*time.Second {
+		t.Errorf("first retry after %v, want 5s", delay)
+	}
+
+	// Nothing is sent before the retry is due
+	if _, err := flushOutbox(now.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("delivered %d times before the retry was due", n)
+	}
+
+	now = next
+	next, err = flushOutbox(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries = readOutbox(t)
+	if len(entries) != 1 || entries[0].Attempts != 2 {
+		t.Fatalf("after the second failure the outbox is %+v", entries)
+	}
+	if delay := next.Sub(time.Now()); delay < 9*time.Second || delay > 10*time.Second {
+		t.Errorf("second retry after %v, want 10s", delay)
+	}
+
+	next, err = flushOutbox(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := readOutbox(t); len(entries) != 0 || !next.IsZero() {
+		t.Errorf("after delivery the outbox is %+v with a retry at %v", entries, next)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("delivered %d times, want 3", n)
+	}
+}
+
+func TestFlushOutboxDropsPermanentFailures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	if err := enqueueOutbox([]outboxEntry{{ID: "a-0", URL: server.URL, Payload: json.RawMessage(`{}`)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := flushOutbox(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := readOutbox(t); len(entries) != 0 || !next.IsZero() {
+		t.Errorf("a rejected event is kept: %+v, retry at %v", entries, next)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("delivered %d times, want 1", n)
+	}
+}
+
+func TestOutboxRetrierSchedulesOneRetry(t *testing.T) {
+	var r outboxRetrier
+	soon := time.Now().Add(time.Minute)
+
+	if r.Update(outboxFlushedMsg{}) != nil {
+		t.Error("a retry was scheduled for an empty outbox")
+	}
+	if r.Update(outboxFlushedMsg{next: soon}) == nil {
+		t.Fatal("no retry was scheduled")
+	}
+	if r.Update(outboxFlushedMsg{next: soon.Add(time.Minute)}) != nil {
+		t.Error("a later retry was scheduled on top of the earlier one")
+	}
+	if r.Update(outboxFlushedMsg{next: soon.Add(-30 * time.Second)}) == nil {
+		t.Error("an earlier retry was not scheduled")
+	}
+	if r.Update(outboxFlushMsg{}) == nil || !r.retryAt.IsZero() {
+		t.Error("flushing did not clear the scheduled retry")
+	}
+}
+
+func TestSessionEvents(t *testing.T) {
+	tests := []struct {
+		event     string
+		completed bool
+		want      string
+	}{
+		{"work_start", false, "session.start"},
+		{"break_start", false, "session.start"},
+		{"work_end", true, "session.complete"},
+		{"work_end", false, "session.skip"},
+		{"work_skip", false, "session.skip"},
+		{"break_skip", false, "session.skip"},
+		{"work_pause", false, ""},
+		{"break_resume", false, ""},
+	}
+	for _, tt := range tests {
+		payloads := sessionEvents([]timerEvent{{Event: tt.event, Completed: tt.completed}})
+		got := ""
+		if len(payloads) > 0 {
+			got = payloads[0].Event
+		}
+		if got != tt.want {
+			t.Errorf("%s (completed %v) sent %q, want %q", tt.event, tt.completed, got, tt.want)
+		}
+	}
+}